@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Pocket/global-services/shared/database"
+	"github.com/Pocket/global-services/shared/utils"
+	"github.com/pokt-foundation/portal-db/types"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+// blockchains is the blockchains config of the server, loaded from the database so the
+// endpoints called while checking, such as the altruists, never come from the payload
+type blockchains struct {
+	dbClient *database.PostgresDBClient
+
+	mu     sync.RWMutex
+	chains map[string]*types.Blockchain
+}
+
+func newBlockchains(ctx context.Context, dbClient *database.PostgresDBClient) (*blockchains, error) {
+	b := &blockchains{dbClient: dbClient}
+	if err := b.load(ctx); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Get returns the config of a blockchain
+func (b *blockchains) Get(id string) (*types.Blockchain, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	blockchain, ok := b.chains[id]
+	return blockchain, ok
+}
+
+// refresh reloads the blockchains on every interval until the context is done,
+// the previous config is kept when they can't be loaded
+func (b *blockchains) refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.load(ctx); err != nil {
+				logger.Log.WithFields(log.Fields{
+					"error": err.Error(),
+				}).Error("perform checks server: error refreshing blockchains: " + err.Error())
+			}
+		}
+	}
+}
+
+func (b *blockchains) load(ctx context.Context) error {
+	blockchainsDB, err := b.dbClient.GetBlockchains(ctx)
+	if err != nil {
+		return err
+	}
+
+	chains := utils.SliceToMappedStruct(blockchainsDB, func(bc *types.Blockchain) string {
+		return bc.ID
+	})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.chains = chains
+
+	return nil
+}
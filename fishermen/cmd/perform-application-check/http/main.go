@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Pocket/global-services/shared/apigateway"
+	"github.com/Pocket/global-services/shared/database"
+	"github.com/Pocket/global-services/shared/environment"
+	"github.com/Pocket/global-services/shared/pocket"
+	"github.com/aws/aws-lambda-go/events"
+	dbclient "github.com/pokt-foundation/db-client/client"
+
+	models "github.com/Pocket/global-services/fishermen/cmd/perform-application-check"
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	port                       = environment.GetString("PORT", "8080")
+	requestTimeout             = time.Duration(environment.GetInt64("TIMEOUT", 120)) * time.Second
+	apiKey                     = environment.GetString("API_KEY", "")
+	maxBodyBytes               = environment.GetInt64("MAX_BODY_BYTES", 10<<20)
+	phdBaseURL                 = environment.GetString("PHD_BASE_URL", "")
+	phdAPIKey                  = environment.GetString("PHD_API_KEY", "")
+	blockchainsRefreshInterval = time.Duration(environment.GetInt64("BLOCKCHAINS_REFRESH_INTERVAL", 300)) * time.Second
+)
+
+// server performs the checks of the payloads it receives, it's the worker of the
+// fishermen's HTTP check executor
+type server struct {
+	deps        *pocket.CheckDependencies
+	blockchains *blockchains
+}
+
+// The server accepts the same payload as the perform-application-check lambda, so the
+// checks can be distributed on any platform
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The server signs relays with the application's key, so only the fishermen can use it
+	if apiKey == "" {
+		logger.Log.Fatal("ERROR STARTING PERFORM CHECKS SERVER: API_KEY is required")
+	}
+
+	dbClient, err := database.NewPHDClient(dbclient.Config{
+		BaseURL: phdBaseURL,
+		APIKey:  phdAPIKey,
+		Version: dbclient.V1,
+	})
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Fatal("ERROR STARTING PERFORM CHECKS SERVER: error validating phd config: " + err.Error())
+	}
+
+	blockchains, err := newBlockchains(ctx, dbClient)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Fatal("ERROR STARTING PERFORM CHECKS SERVER: error obtaining blockchains: " + err.Error())
+	}
+	go blockchains.refresh(ctx, blockchainsRefreshInterval)
+
+	deps, err := models.NewCheckDependencies(ctx, "")
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Fatal("ERROR STARTING PERFORM CHECKS SERVER: " + err.Error())
+	}
+	defer deps.MetricsRecorder.Close()
+
+	s := &server{deps: deps, blockchains: blockchains}
+	mux := http.NewServeMux()
+	mux.HandleFunc(models.PerformChecksPath, s.handlePerformChecks)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, apigateway.NewJSONResponse(http.StatusOK, map[string]interface{}{
+			"ok": true,
+		}))
+	})
+
+	httpServer := &http.Server{
+		Addr:    ":" + port,
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	logger.Log.WithFields(log.Fields{
+		"port": port,
+	}).Info("perform checks server listening")
+
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Log.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Fatal("ERROR RUNNING PERFORM CHECKS SERVER: " + err.Error())
+	}
+}
+
+func (s *server) handlePerformChecks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeResponse(w, apigateway.NewErrorResponse(http.StatusMethodNotAllowed, errors.New("method not allowed")))
+		return
+	}
+	if !isAuthorized(r) {
+		writeResponse(w, apigateway.NewErrorResponse(http.StatusUnauthorized, errors.New("unauthorized")))
+		return
+	}
+
+	var payload []models.Payload
+	body := http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		writeResponse(w, apigateway.NewErrorResponse(http.StatusBadRequest, errors.New("error decoding payload: "+err.Error())))
+		return
+	}
+	if len(payload) == 0 {
+		writeResponse(w, apigateway.NewErrorResponse(http.StatusBadRequest, errors.New("empty payload")))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	// The blockchains of the payload are replaced by the server's own config,
+	// items of blockchains the server doesn't know about are not checked
+	items := make([]*models.ItemResult, len(payload))
+	known := make([]models.Payload, 0, len(payload))
+	knownIndexes := make([]int, 0, len(payload))
+	for index, app := range payload {
+		blockchain, ok := s.blockchains.Get(app.Blockchain.ID)
+		if !ok {
+			items[index] = models.NewItemError(&app, "unknown blockchain")
+			continue
+		}
+
+		app.Blockchain = *blockchain
+		known = append(known, app)
+		knownIndexes = append(knownIndexes, index)
+	}
+
+	if len(known) > 0 {
+		response, err := models.PerformChecks(ctx, *s.deps, known)
+		if err != nil {
+			logger.Log.WithFields(log.Fields{
+				"error":     err.Error(),
+				"requestID": payload[0].RequestID,
+			}).Errorf("perform application check error: %s", err.Error())
+			writeResponse(w, apigateway.NewErrorResponse(http.StatusInternalServerError, err))
+			return
+		}
+
+		for index, item := range response.Items {
+			items[knownIndexes[index]] = item
+		}
+	}
+
+	writeResponse(w, apigateway.NewJSONResponse(http.StatusOK, &models.Response{Items: items}))
+}
+
+// isAuthorized checks the request's bearer token against the server's api key
+func isAuthorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) == 1
+}
+
+// writeResponse writes a response built for the api gateway as a plain HTTP response
+func writeResponse(w http.ResponseWriter, response *events.APIGatewayProxyResponse) {
+	for key, value := range response.Headers {
+		w.Header().Set(key, value)
+	}
+	w.WriteHeader(response.StatusCode)
+	w.Write([]byte(response.Body))
+}
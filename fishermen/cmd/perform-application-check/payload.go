@@ -1,10 +1,17 @@
 package base
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/Pocket/global-services/shared/pocket"
 	"github.com/pokt-foundation/pocket-go/provider"
 	"github.com/pokt-foundation/portal-db/types"
 )
 
+// PerformChecksPath is the route the perform checks servers are served on
+const PerformChecksPath = "/v1/perform-checks"
+
 // Payload is the data needed for the perform-application-check to work
 type Payload struct {
 	Session                provider.Session   `json:"session"`
@@ -12,11 +19,61 @@ type Payload struct {
 	AAT                    provider.PocketAAT `json:"aat"`
 	DefaultAllowance       int                `json:"defaultAllowance"`
 	AltruistTrustThreshold float32            `json:"altruistTrustThreshold"`
+	AltruistMinValidNodes  int                `json:"altruistMinValidNodes"`
 	RequestID              string             `json:"requestID"`
 }
 
+// Status of the checks of an item of the payload
+const (
+	// ItemStatusOK when all the checks were performed
+	ItemStatusOK = "ok"
+	// ItemStatusSkipped when there was nothing to check on the session
+	ItemStatusSkipped = "skipped"
+	// ItemStatusError when the checks couldn't be performed, the item is meant to be retried
+	ItemStatusError = "error"
+)
+
+// ItemKey identifies the session of an app on a chain among the ones of a batch
+func ItemKey(appPublicKey, chain, sessionKey string) string {
+	return fmt.Sprintf("%s-%s-%s", appPublicKey, chain, sessionKey)
+}
+
+// Key returns the item key of the payload
+func (p *Payload) Key() string {
+	appPublicKey := ""
+	if p.Session.Header != nil {
+		appPublicKey = p.Session.Header.AppPublicKey
+	}
+
+	return ItemKey(appPublicKey, p.Blockchain.ID, p.Session.Key)
+}
+
+// ItemResult is the outcome of the checks of an item of the payload
+type ItemResult struct {
+	ApplicationPublicKey string `json:"applicationPublicKey"`
+	Chain                string `json:"chain"`
+	SessionKey           string `json:"sessionKey"`
+	Status               string `json:"status"`
+	// Reason explains why the item was skipped or failed
+	Reason    string    `json:"reason,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	// ElapsedTime is the time in seconds the checks of the item took
+	ElapsedTime float64 `json:"elapsedTime"`
+	// CheckedNodes are the nodes that passed each check, keyed by check name
+	CheckedNodes map[string][]string `json:"checkedNodes"`
+	// LatencyScores are the latencies of the nodes measured while performing the checks
+	LatencyScores []*pocket.NodeLatencyScore `json:"latencyScores"`
+	// NodeResults are the verdicts of the checks on each node, used to keep their check history
+	NodeResults []*pocket.NodeCheckResult `json:"nodeResults"`
+}
+
+// Key returns the item key of the result
+func (r *ItemResult) Key() string {
+	return ItemKey(r.ApplicationPublicKey, r.Chain, r.SessionKey)
+}
+
 // Response represents the output of the perform-application-check lambda
 type Response struct {
-	SyncCheckedNodes  map[string][]string `json:"syncCheckedNodes"`
-	ChainCheckedNodes map[string][]string `json:"chainCheckedNodes"`
+	// Items are the results of each item of the payload, in the same order
+	Items []*ItemResult `json:"items"`
 }
@@ -0,0 +1,216 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pocket/global-services/shared/database"
+	"github.com/Pocket/global-services/shared/environment"
+	"github.com/Pocket/global-services/shared/metrics"
+	"github.com/Pocket/global-services/shared/pocket"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-foundation/pocket-go/relayer"
+	"github.com/pokt-foundation/pocket-go/signer"
+)
+
+var (
+	rpcURL            = environment.GetString("RPC_URL", "")
+	dispatchURLs      = strings.Split(environment.GetString("DISPATCH_URLS", ""), ",")
+	appPrivateKey     = environment.GetString("APPLICATION_PRIVATE_KEY", "")
+	defaultTimeOut    = time.Duration(environment.GetInt64("DEFAULT_TIMEOUT", 8)) * time.Second
+	metricsConnection = environment.GetString("METRICS_CONNECTION", "")
+	challengeEnabled  = environment.GetBool("CHALLENGE_ENABLED", false)
+	challengeMajority = environment.GetInt64("CHALLENGE_MIN_MAJORITY_RESPONSES", 2)
+	challengeMaxConc  = environment.GetInt64("CHALLENGE_MAX_CONCURRENCY", 3)
+)
+
+const (
+	minMetricsPoolSize = 2
+	maxMetricsPoolSize = 2
+)
+
+// NewCheckDependencies returns the clients needed to perform the checks, the metrics
+// recorder returned must be closed by the caller
+func NewCheckDependencies(ctx context.Context, requestID string) (*pocket.CheckDependencies, error) {
+	metricsRecorder, err := metrics.NewMetricsRecorder(ctx, &database.PostgresOptions{
+		Connection:  metricsConnection,
+		MinPoolSize: minMetricsPoolSize,
+		MaxPoolSize: maxMetricsPoolSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rpcProvider := provider.NewProvider(rpcURL, dispatchURLs)
+	rpcProvider.UpdateRequestConfig(0, defaultTimeOut)
+	signer, err := signer.NewSignerFromPrivateKey(appPrivateKey)
+	if err != nil {
+		return nil, errors.New("error creating signer: " + err.Error())
+	}
+
+	var challenger *pocket.Challenger
+	if challengeEnabled {
+		challenger = &pocket.Challenger{
+			ReporterAddress:      signer.GetAddress(),
+			MinMajorityResponses: int(challengeMajority),
+			MaxConcurrency:       int(challengeMaxConc),
+			MetricsRecorder:      metricsRecorder,
+			RequestID:            requestID,
+		}
+	}
+
+	return &pocket.CheckDependencies{
+		Relayer:         relayer.NewRelayer(signer, rpcProvider),
+		MetricsRecorder: metricsRecorder,
+		Challenger:      challenger,
+		RequestID:       requestID,
+	}, nil
+}
+
+// PerformChecks performs the applicable checks on the sessions of the payload, returning
+// the outcome of each of them, the failure of an item doesn't affect the others
+func PerformChecks(ctx context.Context, deps pocket.CheckDependencies, payload []Payload) (*Response, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("empty payload")
+	}
+
+	latencyRecorder := pocket.NewLatencyRecorder()
+	resultRecorder := pocket.NewNodeResultRecorder()
+
+	// Allowance and trust threshold are the same for all the apps of a batch
+	deps.LatencyRecorder = latencyRecorder
+	deps.ResultRecorder = resultRecorder
+	deps.RequestID = payload[0].RequestID
+	deps.DefaultSyncAllowance = payload[0].DefaultAllowance
+	deps.AltruistTrustThreshold = payload[0].AltruistTrustThreshold
+	deps.AltruistMinValidNodes = payload[0].AltruistMinValidNodes
+	if deps.Challenger != nil {
+		challenger := *deps.Challenger
+		challenger.RequestID = deps.RequestID
+		deps.Challenger = &challenger
+	}
+
+	checks, err := pocket.NewChecks(&deps)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &Response{Items: make([]*ItemResult, len(payload))}
+	seen := make(map[string]bool, len(payload))
+
+	var wg sync.WaitGroup
+	for index, application := range payload {
+		result := newItemResult(&application)
+		response.Items[index] = result
+
+		switch {
+		case application.Session.Header == nil || application.Session.Key == "":
+			result.fail("invalid session")
+			continue
+		case seen[result.Key()]:
+			result.fail("duplicated item")
+			continue
+		}
+		seen[result.Key()] = true
+
+		wg.Add(1)
+		go func(app Payload, result *ItemResult) {
+			defer wg.Done()
+			performItemChecks(ctx, &app, pocket.ApplicableChecks(checks, &app.Blockchain), result)
+
+			result.LatencyScores = latencyRecorder.Scores(app.Session.Key)
+			result.NodeResults = resultRecorder.Results(app.Session.Key)
+		}(application, result)
+	}
+	wg.Wait()
+
+	return response, nil
+}
+
+func newItemResult(app *Payload) *ItemResult {
+	result := &ItemResult{
+		Chain:         app.Blockchain.ID,
+		SessionKey:    app.Session.Key,
+		Status:        ItemStatusOK,
+		StartedAt:     time.Now(),
+		CheckedNodes:  make(map[string][]string),
+		LatencyScores: []*pocket.NodeLatencyScore{},
+		NodeResults:   []*pocket.NodeCheckResult{},
+	}
+	if app.Session.Header != nil {
+		result.ApplicationPublicKey = app.Session.Header.AppPublicKey
+	}
+
+	return result
+}
+
+// NewItemError returns the result of an item that couldn't be checked
+func NewItemError(app *Payload, reason string) *ItemResult {
+	result := newItemResult(app)
+	result.fail(reason)
+
+	return result
+}
+
+func (r *ItemResult) fail(reason string) {
+	r.Status = ItemStatusError
+	r.Reason = reason
+}
+
+// performItemChecks runs the checks of a single session, a check panicking or the context
+// ending before all the checks finished fails the item
+func performItemChecks(ctx context.Context, app *Payload, checks []pocket.Check, result *ItemResult) {
+	defer func() {
+		result.ElapsedTime = time.Since(result.StartedAt).Seconds()
+	}()
+
+	switch {
+	case len(app.Session.Nodes) == 0:
+		result.Status, result.Reason = ItemStatusSkipped, "session has no nodes"
+		return
+	case len(checks) == 0:
+		result.Status, result.Reason = ItemStatusSkipped, "no applicable checks"
+		return
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failures := []string{}
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check pocket.Check) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					mu.Lock()
+					defer mu.Unlock()
+					failures = append(failures, fmt.Sprintf("%s panicked: %v", check.Name(), r))
+				}
+			}()
+
+			nodes := check.Run(ctx, &pocket.CheckOptions{
+				Session:    app.Session,
+				PocketAAT:  app.AAT,
+				Blockchain: app.Blockchain,
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.CheckedNodes[check.Name()] = nodes
+		}(check)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		failures = append(failures, "checks interrupted: "+err.Error())
+	}
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		result.fail(strings.Join(failures, "; "))
+	}
+}
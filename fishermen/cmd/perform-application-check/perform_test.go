@@ -0,0 +1,99 @@
+package base
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Pocket/global-services/shared/pocket"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-foundation/portal-db/types"
+	"github.com/stretchr/testify/require"
+)
+
+const testCheckName = "testcheck"
+
+// testCheck passes all the nodes, it panics on chain 0003 and is not applicable to 0002
+type testCheck struct{}
+
+func (t *testCheck) Name() string {
+	return testCheckName
+}
+
+func (t *testCheck) IsApplicable(blockchain *types.Blockchain) bool {
+	return blockchain.ID != "0002"
+}
+
+func (t *testCheck) Run(ctx context.Context, options *pocket.CheckOptions) []string {
+	if options.Blockchain.ID == "0003" {
+		panic("ajua")
+	}
+
+	nodes := []string{}
+	for _, node := range options.Session.Nodes {
+		nodes = append(nodes, node.PublicKey)
+	}
+	return nodes
+}
+
+func (t *testCheck) CachePolicy(sessionKey string) pocket.CachePolicy {
+	return pocket.CachePolicy{}
+}
+
+func init() {
+	pocket.RegisterCheck(testCheckName, func(deps *pocket.CheckDependencies) (pocket.Check, error) {
+		return &testCheck{}, nil
+	})
+}
+
+func newTestPayload(chain, sessionKey string, nodes ...string) Payload {
+	session := provider.Session{
+		Key:    sessionKey,
+		Header: &provider.SessionHeader{AppPublicKey: "app", Chain: chain},
+	}
+	for _, node := range nodes {
+		session.Nodes = append(session.Nodes, &provider.Node{PublicKey: node})
+	}
+
+	return Payload{
+		Session:    session,
+		Blockchain: types.Blockchain{ID: chain},
+	}
+}
+
+func TestPerformChecks(t *testing.T) {
+	c := require.New(t)
+
+	invalid := newTestPayload("0001", "")
+	response, err := PerformChecks(context.Background(), pocket.CheckDependencies{}, []Payload{
+		newTestPayload("0001", "session1", "node1", "node2"),
+		newTestPayload("0001", "session1", "node1", "node2"),
+		newTestPayload("0002", "session2", "node1"),
+		newTestPayload("0003", "session3", "node1"),
+		newTestPayload("0004", "session4"),
+		invalid,
+	})
+	c.NoError(err)
+	c.Len(response.Items, 6)
+
+	c.Equal(ItemStatusOK, response.Items[0].Status)
+	c.Equal(ItemKey("app", "0001", "session1"), response.Items[0].Key())
+	c.Equal([]string{"node1", "node2"}, response.Items[0].CheckedNodes[testCheckName])
+
+	c.Equal(ItemStatusError, response.Items[1].Status)
+	c.Equal("duplicated item", response.Items[1].Reason)
+
+	c.Equal(ItemStatusSkipped, response.Items[2].Status)
+	c.Equal("no applicable checks", response.Items[2].Reason)
+
+	c.Equal(ItemStatusError, response.Items[3].Status)
+	c.Equal("testcheck panicked: ajua", response.Items[3].Reason)
+
+	c.Equal(ItemStatusSkipped, response.Items[4].Status)
+	c.Equal("session has no nodes", response.Items[4].Reason)
+
+	c.Equal(ItemStatusError, response.Items[5].Status)
+	c.Equal("invalid session", response.Items[5].Reason)
+
+	_, err = PerformChecks(context.Background(), pocket.CheckDependencies{}, nil)
+	c.Error(err)
+}
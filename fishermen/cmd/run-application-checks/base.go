@@ -15,6 +15,8 @@ import (
 	"github.com/pokt-foundation/portal-db/types"
 	"github.com/pokt-foundation/utils-go/environment"
 
+	_environment "github.com/Pocket/global-services/shared/environment"
+
 	shared "github.com/Pocket/global-services/shared/error"
 	"github.com/Pocket/global-services/shared/gateway"
 	"github.com/Pocket/global-services/shared/metrics"
@@ -41,8 +43,6 @@ var (
 	maxClientsCacheCheck   = environment.GetInt64("MAX_CLIENTS_CACHE_CHECK", 3)
 	appPrivateKey          = environment.MustGetString("APPLICATION_PRIVATE_KEY")
 	defaultSyncAllowance   = environment.GetInt64("DEFAULT_SYNC_ALLOWANCE", 5)
-	syncCheckKeyPrefix     = environment.GetString("SYNC_CHECK_KEY_PREFIX", "sync-check-")
-	chainheckKeyPrefix     = environment.GetString("CHAIN_CHECK_KEY_PREFIX", "chain-check-")
 	metricsConnection      = environment.GetString("METRICS_CONNECTION", "")
 	minMetricsPoolSize     = environment.GetInt64("MIN_METRICS_POOL_SIZE", 5)
 	maxMetricsPoolSize     = environment.GetInt64("MAX_METRICS_POOL_SIZE", 20)
@@ -50,45 +50,68 @@ var (
 	cacheBatchSize         = environment.GetInt64("CACHE_BATCH_SIZE", 50)
 	phdBaseURL             = environment.MustGetString("PHD_BASE_URL")
 	phdAPIKey              = environment.MustGetString("PHD_API_KEY")
+	altruistTrustThreshold = _environment.GetFloat64("ALTRUIST_TRUST_THRESHOLD", 0.5)
+	altruistMinValidNodes  = environment.GetInt64("ALTRUIST_TRUST_MIN_VALID_NODES", 3)
+	nodeHistorySize        = environment.GetInt64("NODE_HISTORY_SIZE", 10)
+	nodeHistoryPasses      = environment.GetInt64("NODE_HISTORY_REQUIRED_PASSES", 3)
+	nodeHistoryTTL         = environment.GetInt64("NODE_HISTORY_TTL", 86400)
+	deadLetterTTL          = environment.GetInt64("DEAD_LETTER_TTL", 3600)
+	sessionRecheckInterval = environment.GetInt64("SESSION_RECHECK_INTERVAL", 0)
+	sessionRecheckSample   = _environment.GetFloat64("SESSION_RECHECK_SAMPLE_RATE", 0.1)
+	runInterval            = environment.GetInt64("RUN_INTERVAL", 180)
 
 	caches          []*cache.Redis
 	metricsRecorder *metrics.Recorder
 	rpcProvider     *provider.Provider
 )
 
-const (
-	emptyNodesTTL          = 30
-	altruistTrustThreshold = 0.5
-)
-
 // ApplicationData saves all the info needed to run QoS checks on it
 type ApplicationData struct {
-	Caches          []*cache.Redis
-	Provider        *provider.Provider
-	Relayer         *relayer.Relayer
-	MetricsRecorder *metrics.Recorder
-	BlockHeight     int
-	CommitHash      string
-	Blockchains     map[string]*types.Blockchain
-	RequestID       string
-	SyncChecker     *pocket.SyncChecker
-	ChainChecker    *pocket.ChainChecker
-	CacheBatch      chan *cache.Item
+	Caches            []*cache.Redis
+	SessionStores     []gateway.SessionStore
+	Provider          *provider.Provider
+	Relayer           *relayer.Relayer
+	MetricsRecorder   *metrics.Recorder
+	BlockHeight       int
+	CommitHash        string
+	Blockchains       map[string]*types.Blockchain
+	RequestID         string
+	CheckDependencies *pocket.CheckDependencies
+	Checks            []pocket.Check
+	CacheBatch        chan *cache.Item
+	NodeHistories     *NodeHistories
+	DeadLetters       *DeadLetters
+	SessionFreshness  *SessionFreshness
 }
 
 // PerformChecksOptions options for the function that is going to perform the check
 type PerformChecksOptions struct {
-	Ac             *ApplicationData
-	SyncCheckOpts  *pocket.SyncCheckOptions
-	ChainCheckOpts *pocket.ChainCheckOptions
-	Blockchain     types.Blockchain
-	Session        *provider.Session
-	PocketAAT      *provider.PocketAAT
-	CacheTTL       int
-	SyncCheckKey   string
-	ChainCheckKey  string
-	TotalApps      int
-	Invalid        bool
+	Ac *ApplicationData
+	// Checks are the registered checks applicable to the blockchain
+	Checks     []pocket.Check
+	Blockchain types.Blockchain
+	Session    *provider.Session
+	PocketAAT  *provider.PocketAAT
+	Invalid    bool
+}
+
+// CheckOptions returns the options to run the checks on the session
+func (o *PerformChecksOptions) CheckOptions() *pocket.CheckOptions {
+	return &pocket.CheckOptions{
+		Session:    *o.Session,
+		PocketAAT:  *o.PocketAAT,
+		Blockchain: o.Blockchain,
+	}
+}
+
+// GetCheck returns the check with the given name, nil if it's not among the options' checks
+func (o *PerformChecksOptions) GetCheck(name string) pocket.Check {
+	for _, check := range o.Checks {
+		if check.Name() == name {
+			return check
+		}
+	}
+	return nil
 }
 
 // RunApplicationChecks obtains all applicationes needed to run QoS checks, performs them and
@@ -157,107 +180,123 @@ func RunApplicationChecks(ctx context.Context, requestID string, performChecks f
 		RequestID: requestID,
 	})
 
+	checkDependencies := &pocket.CheckDependencies{
+		Relayer:                relayer,
+		MetricsRecorder:        metricsRecorder,
+		LatencyRecorder:        pocket.NewLatencyRecorder(),
+		ResultRecorder:         pocket.NewNodeResultRecorder(),
+		RequestID:              requestID,
+		CacheTTL:               time.Duration(cacheTTL) * time.Second,
+		DefaultSyncAllowance:   int(defaultSyncAllowance),
+		AltruistTrustThreshold: float32(altruistTrustThreshold),
+		AltruistMinValidNodes:  int(altruistMinValidNodes),
+	}
+
+	checks, err := pocket.NewChecks(checkDependencies)
+	if err != nil {
+		return err
+	}
+
 	appChecks := ApplicationData{
-		Caches:          caches,
-		Provider:        rpcProvider,
-		Relayer:         relayer,
-		MetricsRecorder: metricsRecorder,
-		BlockHeight:     blockHeight,
-		RequestID:       requestID,
-		CacheBatch:      cacheBatch,
-		SyncChecker: &pocket.SyncChecker{
-			Relayer:                relayer,
-			DefaultSyncAllowance:   int(defaultSyncAllowance),
-			AltruistTrustThreshold: float32(altruistTrustThreshold),
-			MetricsRecorder:        metricsRecorder,
-			RequestID:              requestID,
+		Caches:            caches,
+		SessionStores:     gateway.NewRedisSessionStores(caches),
+		Provider:          rpcProvider,
+		Relayer:           relayer,
+		MetricsRecorder:   metricsRecorder,
+		BlockHeight:       blockHeight,
+		RequestID:         requestID,
+		CacheBatch:        cacheBatch,
+		CheckDependencies: checkDependencies,
+		Checks:            checks,
+		NodeHistories: &NodeHistories{
+			Caches:     caches,
+			CacheBatch: cacheBatch,
+			RunID:      requestID,
+			Policy: pocket.NodeHistoryPolicy{
+				Size:           int(nodeHistorySize),
+				RequiredPasses: int(nodeHistoryPasses),
+			},
+			TTL: time.Duration(nodeHistoryTTL) * time.Second,
+		},
+		DeadLetters: &DeadLetters{
+			Caches:    caches,
+			RequestID: requestID,
+			TTL:       time.Duration(deadLetterTTL) * time.Second,
 		},
-		ChainChecker: &pocket.ChainChecker{
-			Relayer:         relayer,
-			MetricsRecorder: metricsRecorder,
+		SessionFreshness: &SessionFreshness{
+			Caches:          caches,
+			RecheckInterval: time.Duration(sessionRecheckInterval) * time.Second,
+			RunInterval:     time.Duration(runInterval) * time.Second,
+			SampleRate:      sessionRecheckSample,
 			RequestID:       requestID,
 		},
 	}
 
-	totalApps := 0
-	for _, app := range ntApps {
-		for range app.Chains {
-			totalApps++
-		}
-	}
-
 	var wg sync.WaitGroup
 	sem := semaphore.NewWeighted(dispatchConcurrency)
 
+	targets := []checkTarget{}
 	for index, app := range ntApps {
-		app := app
-		index := index
 		for _, chain := range app.Chains {
-			wg.Add(1)
-			sem.Acquire(ctx, 1)
-			go func(publicKey, ch string, idx int) {
-				defer wg.Done()
-				defer sem.Release(1)
-
-				session, err := appChecks.getSession(ctx, publicKey, ch)
-
-				if err != nil {
-					// Such sessions cannot be dispatched so not an actual error
-					if strings.Contains(err.Error(), errLessThanMinimumNodes.Error()) {
-						return
-					}
-
-					logger.Log.WithFields(log.Fields{
-						"appPublicKey": publicKey,
-						"chain":        ch,
-						"error":        err.Error(),
-					}).Errorf("error dispatching: %s", err.Error())
-					return
-				}
+			targets = append(targets, checkTarget{publicKey: app.PublicKey, chain: chain, index: index})
+		}
+	}
+	targets = prioritizeDeadLetters(targets, appChecks.DeadLetters.Pop(ctx))
 
-				dbApp := dbApps[idx]
-				pocketAAT := provider.PocketAAT{
-					AppPubKey:    dbApp.GatewayAAT.ApplicationPublicKey,
-					ClientPubKey: dbApp.GatewayAAT.ClientPublicKey,
-					Version:      dbApp.GatewayAAT.Version,
-					Signature:    dbApp.GatewayAAT.ApplicationSignature,
-				}
-				blockchain, ok := blockchains[ch]
-				if !ok {
+	for _, target := range targets {
+		wg.Add(1)
+		sem.Acquire(ctx, 1)
+		go func(publicKey, ch string, idx int) {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			session, err := appChecks.getSession(ctx, publicKey, ch)
+
+			if err != nil {
+				// Such sessions cannot be dispatched so not an actual error
+				if strings.Contains(err.Error(), errLessThanMinimumNodes.Error()) {
 					return
 				}
 
-				performChecks(ctx, &PerformChecksOptions{
-					Ac: &appChecks,
-					SyncCheckOpts: &pocket.SyncCheckOptions{
-						Session:          *session,
-						PocketAAT:        pocketAAT,
-						SyncCheckOptions: blockchain.SyncCheckOptions,
-						AltruistURL:      blockchain.Altruist,
-						Blockchain:       blockchain.ID,
-					},
-					ChainCheckOpts: &pocket.ChainCheckOptions{
-						Session:    *session,
-						Blockchain: blockchain.ID,
-						Data:       blockchain.ChainIDCheck,
-						ChainID:    blockchain.ChainID,
-						Path:       blockchain.Path,
-						PocketAAT:  pocketAAT,
-					},
-					SyncCheckKey:  appChecks.CommitHash + syncCheckKeyPrefix + session.Key,
-					ChainCheckKey: appChecks.CommitHash + chainheckKeyPrefix + session.Key,
-					CacheTTL:      int(cacheTTL),
-					Blockchain:    *blockchain,
-					Session:       session,
-					PocketAAT:     &pocketAAT,
-					TotalApps:     totalApps,
-					Invalid:       err != nil,
-				})
-			}(app.PublicKey, chain, index)
-		}
+				logger.Log.WithFields(log.Fields{
+					"appPublicKey": publicKey,
+					"chain":        ch,
+					"error":        err.Error(),
+				}).Errorf("error dispatching: %s", err.Error())
+				return
+			}
+
+			dbApp := dbApps[idx]
+			pocketAAT := provider.PocketAAT{
+				AppPubKey:    dbApp.GatewayAAT.ApplicationPublicKey,
+				ClientPubKey: dbApp.GatewayAAT.ClientPublicKey,
+				Version:      dbApp.GatewayAAT.Version,
+				Signature:    dbApp.GatewayAAT.ApplicationSignature,
+			}
+			blockchain, ok := blockchains[ch]
+			if !ok {
+				return
+			}
+
+			applicableChecks := pocket.ApplicableChecks(appChecks.Checks, blockchain)
+			if !appChecks.SessionFreshness.ShouldCheck(ctx, session, applicableChecks) {
+				return
+			}
+
+			performChecks(ctx, &PerformChecksOptions{
+				Ac:         &appChecks,
+				Checks:     applicableChecks,
+				Blockchain: *blockchain,
+				Session:    session,
+				PocketAAT:  &pocketAAT,
+				Invalid:    err != nil,
+			})
+		}(target.publicKey, target.chain, target.index)
 	}
 	wg.Wait()
 
+	appChecks.SessionFreshness.LogSummary()
+
 	close(cacheBatch)
 	cacheWg.Wait()
 
@@ -266,8 +305,12 @@ func RunApplicationChecks(ctx context.Context, requestID string, performChecks f
 }
 
 func (ac *ApplicationData) getSession(ctx context.Context, publicKey, chain string) (*provider.Session, error) {
-	_, cachedSession := gateway.ShouldDispatch(ctx, ac.Caches, ac.BlockHeight,
-		gateway.GetSessionCacheKey(publicKey, chain, ac.CommitHash), int(maxClientsCacheCheck))
+	_, cachedSession := gateway.ShouldDispatch(ctx, ac.SessionStores, gateway.ShouldDispatchOptions{
+		BlockHeight: ac.BlockHeight,
+		Key:         gateway.GetSessionCacheKey(publicKey, chain, ac.CommitHash),
+		MaxClients:  int(maxClientsCacheCheck),
+		Policy:      gateway.FreshnessPolicy{Mode: gateway.FreshnessAll},
+	})
 
 	if cachedSession != nil {
 		return cachedSession, nil
@@ -286,7 +329,7 @@ func (ac *ApplicationData) getSession(ctx context.Context, publicKey, chain stri
 }
 
 // EraseNodesFailureMark deletes the failure status on nodes on the api that were failing
-// a significant amount of relays
+// a significant amount of relays, nodes penalized by their check history must not be given
 func EraseNodesFailureMark(nodes []string, blockchain, commitHash string, cacheBatch chan *cache.Item) {
 	nodeFailureKey := func(blockchain, commitHash, node string) string {
 		return fmt.Sprintf("%s{%s}-%s-failure", commitHash, blockchain, node)
@@ -300,21 +343,81 @@ func EraseNodesFailureMark(nodes []string, blockchain, commitHash string, cacheB
 	}
 }
 
-// CacheNodes inserts a into the cache batch channel
-func CacheNodes(nodes []string, batch chan *cache.Item, key string, ttl int) error {
-	marshalledNodes, err := json.Marshal(nodes)
-	if err != nil {
-		return err
+// RunChecks performs all the checks of the options concurrently and caches their results
+func RunChecks(ctx context.Context, options *PerformChecksOptions) {
+	checkedNodes := make([][]string, len(options.Checks))
+
+	var wg sync.WaitGroup
+	for idx, check := range options.Checks {
+		wg.Add(1)
+		go func(idx int, check pocket.Check) {
+			defer wg.Done()
+			checkedNodes[idx] = check.Run(ctx, options.CheckOptions())
+		}(idx, check)
 	}
+	wg.Wait()
+
+	penalized := options.Ac.NodeHistories.Update(ctx,
+		options.Ac.CheckDependencies.ResultRecorder.Results(options.Session.Key))
+
+	for idx, check := range options.Checks {
+		CacheCheckResults(check, FilterPenalizedNodes(check, checkedNodes[idx], penalized, options), options)
+	}
+
+	CacheLatencyScores(options.Ac, options.Ac.CheckDependencies.LatencyRecorder.Scores(options.Session.Key))
+}
 
+// CacheCheckResults sends the nodes that passed a check to be cached following the check's cache policy
+func CacheCheckResults(check pocket.Check, nodes []string, options *PerformChecksOptions) {
+	policy := check.CachePolicy(options.Session.Key)
+
+	ttl := policy.TTL
 	if len(nodes) == 0 {
-		ttl = emptyNodesTTL
+		ttl = policy.EmptyTTL
 	}
-	batch <- &cache.Item{
-		Key:   key,
+
+	marshalledNodes, err := json.Marshal(nodes)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"error":        err.Error(),
+			"requestID":    options.Ac.RequestID,
+			"blockchainID": options.Blockchain.ID,
+			"sessionKey":   options.Session.Key,
+			"check":        check.Name(),
+		}).Error("perform checks: error marshalling checked nodes: " + err.Error())
+		return
+	}
+
+	options.Ac.CacheBatch <- &cache.Item{
+		Key:   policy.Key,
 		Value: marshalledNodes,
-		TTL:   time.Duration(ttl) * time.Second,
+		TTL:   ttl,
 	}
 
-	return nil
+	if policy.EraseFailureMarks {
+		EraseNodesFailureMark(nodes, options.Blockchain.ID, options.Ac.CommitHash, options.Ac.CacheBatch)
+	}
+}
+
+// CacheLatencyScores sends the latency scores of the nodes to be cached, so nodes can be
+// cherry picked before serving any relay
+func CacheLatencyScores(ac *ApplicationData, scores []*pocket.NodeLatencyScore) {
+	for _, score := range scores {
+		marshalledScore, err := json.Marshal(score)
+		if err != nil {
+			logger.Log.WithFields(log.Fields{
+				"error":        err.Error(),
+				"requestID":    ac.RequestID,
+				"blockchainID": score.Chain,
+				"sessionKey":   score.SessionKey,
+			}).Error("perform checks: error marshalling latency score: " + err.Error())
+			continue
+		}
+
+		ac.CacheBatch <- &cache.Item{
+			Key:   pocket.NodeLatencyScoreKey(ac.CommitHash, score.Chain, score.NodePublicKey),
+			Value: marshalledScore,
+			TTL:   time.Duration(cacheTTL) * time.Second,
+		}
+	}
 }
@@ -0,0 +1,277 @@
+package base
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Pocket/global-services/shared/utils"
+	"github.com/pokt-foundation/utils-go/environment"
+
+	performAppCheck "github.com/Pocket/global-services/fishermen/cmd/perform-application-check"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+const deadLetterTimeout = 5 * time.Second
+
+var (
+	checksPerInvoke      = environment.GetInt64("CHECKS_PER_INVOKE", 10)
+	checksBatchMaxWait   = environment.GetInt64("CHECKS_BATCH_MAX_WAIT_MS", 2000)
+	maxConcurrentInvokes = environment.GetInt64("MAX_CONCURRENT_INVOKES", 10)
+	checkRetries         = environment.GetInt64("CHECK_RETRIES", 2)
+	checkRetryBackoff    = environment.GetInt64("CHECK_RETRY_BACKOFF_MS", 500)
+	checkRetryMaxBackoff = environment.GetInt64("CHECK_RETRY_MAX_BACKOFF_MS", 5000)
+)
+
+type applicationData struct {
+	payload *performAppCheck.Payload
+	config  *PerformChecksOptions
+	wg      *sync.WaitGroup
+	// failure is the reason of the last failed attempt to check the session
+	failure string
+}
+
+// CheckBatcher groups the sessions to check in batches which are performed by a check executor
+type CheckBatcher struct {
+	executor  CheckExecutor
+	requestID string
+	batcher   *utils.Batcher[*applicationData]
+}
+
+// NewCheckBatcher returns a batcher that performs the checks of up to CHECKS_PER_INVOKE
+// sessions at once with the executor, it must be closed once all the sessions were added
+func NewCheckBatcher(ctx context.Context, requestID string, executor CheckExecutor) *CheckBatcher {
+	b := &CheckBatcher{
+		executor:  executor,
+		requestID: requestID,
+	}
+	b.batcher = utils.NewBatcher(ctx, utils.BatcherOptions{
+		MaxSize:              int(checksPerInvoke),
+		MaxWait:              time.Duration(checksBatchMaxWait) * time.Millisecond,
+		MaxConcurrentFlushes: int(maxConcurrentInvokes),
+	}, b.performChecks)
+
+	return b
+}
+
+// PerformChecks adds the session to the current batch and waits until its checks are
+// performed, meant to be given to RunApplicationChecks
+func (b *CheckBatcher) PerformChecks(ctx context.Context, options *PerformChecksOptions) {
+	if options.Invalid {
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	app := &applicationData{
+		payload: &performAppCheck.Payload{
+			Session:                *options.Session,
+			Blockchain:             options.Blockchain,
+			AAT:                    *options.PocketAAT,
+			DefaultAllowance:       options.Ac.CheckDependencies.DefaultSyncAllowance,
+			AltruistTrustThreshold: options.Ac.CheckDependencies.AltruistTrustThreshold,
+			AltruistMinValidNodes:  options.Ac.CheckDependencies.AltruistMinValidNodes,
+			RequestID:              options.Ac.RequestID,
+		},
+		config: options,
+		wg:     &wg,
+	}
+	if err := b.batcher.Add(app); err != nil {
+		logger.Log.WithFields(log.Fields{
+			"requestID":    b.requestID,
+			"appPublicKey": options.Session.Header.AppPublicKey,
+			"chain":        options.Blockchain.ID,
+			"error":        err.Error(),
+		}).Error("perform checks: error batching session: " + err.Error())
+
+		// The session was never checked, so the next run checks it first
+		app.failure = "batcher closed"
+		b.deadLetter([]*applicationData{app}, 0)
+		return
+	}
+	wg.Wait()
+}
+
+// Close performs the checks of the pending sessions and waits for all the batches to finish
+func (b *CheckBatcher) Close() {
+	b.batcher.Close()
+}
+
+func (b *CheckBatcher) performChecks(ctx context.Context, batch []*applicationData) {
+	apps := make(map[string]*applicationData, len(batch))
+	for _, app := range batch {
+		defer app.wg.Done()
+		apps[app.payload.Key()] = app
+	}
+
+	b.executeWithRetries(ctx, apps, batch, 0)
+}
+
+// executeWithRetries performs the checks of the sessions, retrying the failed ones with backoff.
+// Batches whose execution fails are split in half to isolate the payloads making them fail,
+// the sessions still failing after all the retries are sent to the dead letter list.
+func (b *CheckBatcher) executeWithRetries(ctx context.Context, apps map[string]*applicationData, pending []*applicationData, attempt int) {
+	if attempt > 0 {
+		select {
+		case <-time.After(retryBackoff(attempt)):
+		case <-ctx.Done():
+			b.deadLetter(pending, attempt)
+			return
+		}
+	}
+
+	payloads := make([]*performAppCheck.Payload, 0, len(pending))
+	for _, app := range pending {
+		payloads = append(payloads, app.payload)
+	}
+
+	response, err := b.executor.Execute(ctx, payloads)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"requestID": b.requestID,
+			"items":     len(pending),
+			"attempt":   attempt,
+			"error":     err.Error(),
+		}).Error("perform checks: error executing checks: " + err.Error())
+
+		for _, app := range pending {
+			app.failure = err.Error()
+		}
+
+		switch {
+		case attempt >= int(checkRetries):
+			b.deadLetter(pending, attempt+1)
+		case len(pending) == 1:
+			b.executeWithRetries(ctx, apps, pending, attempt+1)
+		default:
+			half := len(pending) / 2
+			b.executeWithRetries(ctx, apps, pending[:half], attempt+1)
+			b.executeWithRetries(ctx, apps, pending[half:], attempt+1)
+		}
+		return
+	}
+
+	failed := b.handleItemResults(ctx, apps, pending, response.Items)
+	switch {
+	case len(failed) == 0:
+		return
+	case attempt >= int(checkRetries):
+		b.deadLetter(failed, attempt+1)
+	default:
+		b.executeWithRetries(ctx, apps, failed, attempt+1)
+	}
+}
+
+// retryBackoff returns the exponential wait before the given retry, bounded by the max backoff
+func retryBackoff(attempt int) time.Duration {
+	backoff := time.Duration(checkRetryBackoff) * time.Millisecond
+	maxBackoff := time.Duration(checkRetryMaxBackoff) * time.Millisecond
+
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return utils.Min(backoff, maxBackoff)
+}
+
+// deadLetter sends the sessions to the dead letter list so the next run checks them first
+func (b *CheckBatcher) deadLetter(failed []*applicationData, attempts int) {
+	if len(failed) == 0 {
+		return
+	}
+
+	letters := make([]*DeadLetter, 0, len(failed))
+	for _, app := range failed {
+		letters = append(letters, &DeadLetter{
+			ApplicationPublicKey: app.payload.Session.Header.AppPublicKey,
+			Chain:                app.payload.Blockchain.ID,
+			SessionKey:           app.payload.Session.Key,
+			Reason:               app.failure,
+			Attempts:             attempts,
+			Timestamp:            time.Now(),
+		})
+	}
+
+	// The run's context may already be done, which is one of the reasons to dead letter
+	ctx, cancel := context.WithTimeout(context.Background(), deadLetterTimeout)
+	defer cancel()
+
+	err := failed[0].config.Ac.DeadLetters.Push(ctx, letters)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"requestID": b.requestID,
+			"items":     len(letters),
+			"error":     err.Error(),
+		}).Error("perform checks: error writing dead letters: " + err.Error())
+		return
+	}
+
+	logger.Log.WithFields(log.Fields{
+		"requestID": b.requestID,
+		"items":     len(letters),
+		"attempts":  attempts,
+	}).Warn("perform checks: sessions sent to dead letters")
+}
+
+// handleItemResults caches the results of the items that succeeded, returning the ones to retry
+func (b *CheckBatcher) handleItemResults(ctx context.Context, apps map[string]*applicationData, pending []*applicationData, items []*performAppCheck.ItemResult) []*applicationData {
+	answered := make(map[string]bool, len(items))
+	failed := []*applicationData{}
+
+	for _, item := range items {
+		app, ok := apps[item.Key()]
+		if !ok || answered[item.Key()] {
+			continue
+		}
+		answered[item.Key()] = true
+
+		fields := log.Fields{
+			"requestID":    b.requestID,
+			"appPublicKey": item.ApplicationPublicKey,
+			"chain":        item.Chain,
+			"sessionKey":   item.SessionKey,
+			"status":       item.Status,
+			"reason":       item.Reason,
+			"elapsedTime":  item.ElapsedTime,
+		}
+
+		switch item.Status {
+		case performAppCheck.ItemStatusOK:
+			cacheItemResult(ctx, app.config, item)
+		case performAppCheck.ItemStatusSkipped:
+			logger.Log.WithFields(fields).Info("perform checks: item skipped: " + item.Reason)
+		default:
+			logger.Log.WithFields(fields).Warn("perform checks: item failed: " + item.Reason)
+			app.failure = item.Reason
+			failed = append(failed, app)
+		}
+	}
+
+	for _, app := range pending {
+		if !answered[app.payload.Key()] {
+			app.failure = "missing from the checks response"
+			failed = append(failed, app)
+		}
+	}
+
+	return failed
+}
+
+// cacheItemResult updates the check history of the nodes of the session and caches
+// the nodes that passed each check along with their latency
+func cacheItemResult(ctx context.Context, options *PerformChecksOptions, item *performAppCheck.ItemResult) {
+	penalized := options.Ac.NodeHistories.Update(ctx, item.NodeResults)
+
+	for checkName, nodes := range item.CheckedNodes {
+		check := options.GetCheck(checkName)
+		if check == nil {
+			continue
+		}
+
+		CacheCheckResults(check, FilterPenalizedNodes(check, nodes, penalized, options), options)
+	}
+
+	CacheLatencyScores(options.Ac, item.LatencyScores)
+}
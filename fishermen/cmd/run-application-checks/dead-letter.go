@@ -0,0 +1,153 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Pocket/global-services/shared/cache"
+	"github.com/Pocket/global-services/shared/utils"
+	"github.com/go-redis/redis/v8"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeadLetter is a session whose checks kept failing after all the retries
+type DeadLetter struct {
+	ApplicationPublicKey string    `json:"applicationPublicKey"`
+	Chain                string    `json:"chain"`
+	SessionKey           string    `json:"sessionKey"`
+	Reason               string    `json:"reason"`
+	Attempts             int       `json:"attempts"`
+	Timestamp            time.Time `json:"timestamp"`
+}
+
+// DeadLetterKey returns the key of the list the dead letters are kept on
+func DeadLetterKey(commitHash string) string {
+	return fmt.Sprintf("%s{fishermen}-check-dead-letters", commitHash)
+}
+
+// DeadLetters is the list of the sessions that failed on a run, the next run checks
+// them before any other session
+type DeadLetters struct {
+	Caches     []*cache.Redis
+	CommitHash string
+	RequestID  string
+	TTL        time.Duration
+}
+
+// Push adds the sessions to the dead letter list of all the caches
+func (dl *DeadLetters) Push(ctx context.Context, letters []*DeadLetter) error {
+	if dl == nil || len(letters) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, 0, len(letters))
+	for _, letter := range letters {
+		marshalledLetter, err := json.Marshal(letter)
+		if err != nil {
+			return errors.New("error marshalling dead letter: " + err.Error())
+		}
+		values = append(values, marshalledLetter)
+	}
+
+	key := DeadLetterKey(dl.CommitHash)
+	return utils.RunFnOnSliceSingleFailure(dl.Caches, func(c *cache.Redis) error {
+		_, err := c.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.RPush(ctx, key, values...)
+			pipe.Expire(ctx, key, dl.TTL)
+			return nil
+		})
+		return err
+	})
+}
+
+// Pop takes all the dead letters out of the caches, each session is returned once
+func (dl *DeadLetters) Pop(ctx context.Context) []*DeadLetter {
+	if dl == nil {
+		return nil
+	}
+
+	var mu sync.Mutex
+	key := DeadLetterKey(dl.CommitHash)
+	rawLetters := []string{}
+
+	errs := utils.RunFnOnSliceMultipleFailures(dl.Caches, func(c *cache.Redis) error {
+		var values *redis.StringSliceCmd
+		_, err := c.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			values = pipe.LRange(ctx, key, 0, -1)
+			pipe.Del(ctx, key)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		rawLetters = append(rawLetters, values.Val()...)
+		return nil
+	})
+	for _, err := range errs {
+		if err != nil {
+			logger.Log.WithFields(log.Fields{
+				"error":     err.Error(),
+				"requestID": dl.RequestID,
+			}).Error("dead letters: error reading dead letters: " + err.Error())
+		}
+	}
+
+	seen := make(map[string]bool)
+	letters := []*DeadLetter{}
+	for _, rawLetter := range rawLetters {
+		var letter DeadLetter
+		if err := json.Unmarshal([]byte(rawLetter), &letter); err != nil {
+			continue
+		}
+
+		key := letter.ApplicationPublicKey + "-" + letter.Chain
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		letters = append(letters, &letter)
+	}
+
+	return letters
+}
+
+// checkTarget is an app chain to check on a run
+type checkTarget struct {
+	publicKey string
+	chain     string
+	index     int
+}
+
+// prioritizeDeadLetters moves the targets that were dead lettered on the previous run
+// to the front, keeping the order of the rest
+func prioritizeDeadLetters(targets []checkTarget, letters []*DeadLetter) []checkTarget {
+	if len(letters) == 0 {
+		return targets
+	}
+
+	deadLettered := make(map[string]bool, len(letters))
+	for _, letter := range letters {
+		deadLettered[letter.ApplicationPublicKey+"-"+letter.Chain] = true
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		return deadLettered[targets[i].publicKey+"-"+targets[i].chain] &&
+			!deadLettered[targets[j].publicKey+"-"+targets[j].chain]
+	})
+
+	logger.Log.WithFields(log.Fields{
+		"deadLetters": len(letters),
+	}).Info("dead letters: checking the sessions that failed on the previous run first")
+
+	return targets
+}
@@ -0,0 +1,198 @@
+package base
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Pocket/global-services/shared/pocket"
+	"github.com/Pocket/global-services/shared/utils"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pokt-foundation/utils-go/environment"
+
+	performAppCheck "github.com/Pocket/global-services/fishermen/cmd/perform-application-check"
+	awsLambda "github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// Check executors available
+const (
+	// ExecutorInProcess performs the checks on the same process
+	ExecutorInProcess = "inprocess"
+	// ExecutorHTTP sends the checks to a pool of perform-application-check http servers
+	ExecutorHTTP = "http"
+	// ExecutorLambda invokes the perform-application-check lambda
+	ExecutorLambda = "lambda"
+)
+
+var (
+	region                   = environment.GetString("AWS_REGION", "")
+	performCheckFunctionName = environment.GetString("PERFORM_CHECK_FUNCTION_NAME", "")
+	performCheckURLs         = environment.GetString("PERFORM_CHECK_URLS", "")
+	performCheckAPIKey       = environment.GetString("PERFORM_CHECK_API_KEY", "")
+	performCheckTimeout      = environment.GetInt64("PERFORM_CHECK_TIMEOUT", 120)
+)
+
+// CheckExecutor performs the checks of a batch of application sessions
+type CheckExecutor interface {
+	Execute(ctx context.Context, payloads []*performAppCheck.Payload) (*performAppCheck.Response, error)
+	// Close releases the clients of the executor
+	Close()
+}
+
+// NewCheckExecutor returns the check executor of the given kind configured from the environment
+func NewCheckExecutor(ctx context.Context, kind string) (CheckExecutor, error) {
+	switch kind {
+	case ExecutorInProcess:
+		deps, err := performAppCheck.NewCheckDependencies(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		return &InProcessExecutor{Deps: deps}, nil
+	case ExecutorHTTP:
+		if performCheckURLs == "" {
+			return nil, errors.New("no perform check urls")
+		}
+		if performCheckAPIKey == "" {
+			return nil, errors.New("no perform check api key")
+		}
+		return &HTTPExecutor{
+			URLs:   strings.Split(performCheckURLs, ","),
+			APIKey: performCheckAPIKey,
+			Client: &http.Client{Timeout: time.Duration(performCheckTimeout) * time.Second},
+		}, nil
+	case ExecutorLambda:
+		sess, err := session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, errors.New("error creating aws session: " + err.Error())
+		}
+		return &LambdaExecutor{
+			Client:       awsLambda.New(sess, &aws.Config{Region: aws.String(region)}),
+			FunctionName: performCheckFunctionName,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("invalid check executor %s", kind)
+}
+
+// InProcessExecutor performs the checks on the same process, the dependencies are shared by all the batches
+type InProcessExecutor struct {
+	Deps *pocket.CheckDependencies
+}
+
+// Execute performs the checks of the payloads
+func (e *InProcessExecutor) Execute(ctx context.Context, payloads []*performAppCheck.Payload) (*performAppCheck.Response, error) {
+	payload := make([]performAppCheck.Payload, 0, len(payloads))
+	for _, p := range payloads {
+		payload = append(payload, *p)
+	}
+
+	return performAppCheck.PerformChecks(ctx, *e.Deps, payload)
+}
+
+// Close closes the metrics recorder of the dependencies
+func (e *InProcessExecutor) Close() {
+	e.Deps.MetricsRecorder.Close()
+}
+
+// HTTPExecutor sends the checks to a pool of perform-application-check http servers,
+// batches are distributed among the servers in a round robin
+type HTTPExecutor struct {
+	// URLs are the base urls of the servers
+	URLs   []string
+	APIKey string
+	Client *http.Client
+
+	next uint32
+}
+
+// Execute sends the payloads to be checked by the next server of the pool
+func (e *HTTPExecutor) Execute(ctx context.Context, payloads []*performAppCheck.Payload) (*performAppCheck.Response, error) {
+	body, err := json.Marshal(payloads)
+	if err != nil {
+		return nil, errors.New("error marshalling payload: " + err.Error())
+	}
+
+	url := strings.TrimSuffix(e.URLs[int(atomic.AddUint32(&e.next, 1)-1)%len(e.URLs)], "/") +
+		performAppCheck.PerformChecksPath
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, errors.New("error making perform checks request: " + err.Error())
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+e.APIKey)
+
+	res, err := e.Client.Do(req)
+	defer utils.CloseOrLog(res)
+	if err != nil {
+		return nil, errors.New("error performing perform checks request: " + err.Error())
+	}
+
+	rawResponse, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.New("error reading perform checks response: " + err.Error())
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("perform checks server %s returned %d: %s",
+			utils.GetDomainFromURL(url), res.StatusCode, string(rawResponse))
+	}
+
+	var response *performAppCheck.Response
+	if err := json.Unmarshal(rawResponse, &response); err != nil {
+		return nil, errors.New("error unmarshalling perform checks response: " + err.Error())
+	}
+
+	return response, nil
+}
+
+// Close closes the idle connections to the servers
+func (e *HTTPExecutor) Close() {
+	e.Client.CloseIdleConnections()
+}
+
+// LambdaExecutor invokes the perform-application-check lambda
+type LambdaExecutor struct {
+	Client       *awsLambda.Lambda
+	FunctionName string
+}
+
+// Execute invokes the lambda with the payloads
+func (e *LambdaExecutor) Execute(ctx context.Context, payloads []*performAppCheck.Payload) (*performAppCheck.Response, error) {
+	payload, err := json.Marshal(payloads)
+	if err != nil {
+		return nil, errors.New("error marshalling payload: " + err.Error())
+	}
+
+	result, err := e.Client.InvokeWithContext(ctx, &awsLambda.InvokeInput{
+		FunctionName: aws.String(e.FunctionName), Payload: payload})
+	if err != nil {
+		return nil, errors.New("error invoking lambda: " + err.Error())
+	}
+
+	var response events.APIGatewayProxyResponse
+	if err = json.Unmarshal(result.Payload, &response); err != nil {
+		return nil, errors.New("error unmarshalling invoke response: " + err.Error())
+	}
+
+	var validNodes *performAppCheck.Response
+	if err = json.Unmarshal([]byte(response.Body), &validNodes); err != nil {
+		return nil, errors.New("error unmarshalling valid nodes: " + err.Error())
+	}
+
+	return validNodes, nil
+}
+
+// Close is a no-op, the lambda client has nothing to release
+func (e *LambdaExecutor) Close() {}
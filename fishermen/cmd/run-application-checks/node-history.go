@@ -0,0 +1,166 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Pocket/global-services/shared/cache"
+	"github.com/Pocket/global-services/shared/pocket"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+// NodeHistories keeps the check history of the nodes checked on a run, histories are
+// loaded from the cache the first time a node is seen and written back after every update
+type NodeHistories struct {
+	Caches     []*cache.Redis
+	CacheBatch chan *cache.Item
+	CommitHash string
+	RunID      string
+	Policy     pocket.NodeHistoryPolicy
+	TTL        time.Duration
+
+	mu        sync.Mutex
+	histories map[string]*pocket.NodeHistory
+}
+
+// Update adds the check results to the history of their nodes, returning the
+// nodes that are penalized and must not be admitted yet
+func (nh *NodeHistories) Update(ctx context.Context, results []*pocket.NodeCheckResult) map[string]bool {
+	penalized := make(map[string]bool)
+	if nh == nil || len(results) == 0 {
+		return penalized
+	}
+
+	// The histories are read without holding the lock so concurrent batches
+	// don't wait on each other's cache round trip
+	loaded := nh.loadHistories(ctx, nh.missingKeys(results))
+
+	nh.mu.Lock()
+	defer nh.mu.Unlock()
+
+	for key, history := range loaded {
+		// Another batch may have loaded and updated the node in the meantime
+		if _, ok := nh.histories[key]; !ok {
+			nh.histories[key] = history
+		}
+	}
+
+	updated := make(map[string]*pocket.NodeHistory)
+	for _, result := range results {
+		key := pocket.NodeHistoryKey(nh.CommitHash, result.Chain, result.NodePublicKey)
+		history := nh.histories[key]
+		history.Add(result, nh.RunID, nh.Policy)
+		updated[key] = history
+	}
+
+	for key, history := range updated {
+		marshalledHistory, err := json.Marshal(history)
+		if err != nil {
+			logger.Log.WithFields(log.Fields{
+				"error":     err.Error(),
+				"requestID": nh.RunID,
+				"key":       key,
+			}).Error("perform checks: error marshalling node history: " + err.Error())
+			continue
+		}
+
+		nh.CacheBatch <- &cache.Item{
+			Key:   key,
+			Value: marshalledHistory,
+			TTL:   nh.TTL,
+		}
+	}
+
+	for _, result := range results {
+		key := pocket.NodeHistoryKey(nh.CommitHash, result.Chain, result.NodePublicKey)
+		if !nh.histories[key].IsAdmitted() {
+			penalized[result.NodePublicKey] = true
+		}
+	}
+
+	return penalized
+}
+
+// missingKeys returns the history keys of the nodes not seen before on the run
+func (nh *NodeHistories) missingKeys(results []*pocket.NodeCheckResult) []string {
+	nh.mu.Lock()
+	defer nh.mu.Unlock()
+
+	if nh.histories == nil {
+		nh.histories = make(map[string]*pocket.NodeHistory)
+	}
+
+	seen := make(map[string]bool)
+	keys := []string{}
+	for _, result := range results {
+		key := pocket.NodeHistoryKey(nh.CommitHash, result.Chain, result.NodePublicKey)
+		if _, ok := nh.histories[key]; ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// loadHistories reads the histories of the given keys from the cache,
+// nodes without a history start with an empty one
+func (nh *NodeHistories) loadHistories(ctx context.Context, keys []string) map[string]*pocket.NodeHistory {
+	histories := make(map[string]*pocket.NodeHistory, len(keys))
+	for _, key := range keys {
+		histories[key] = &pocket.NodeHistory{}
+	}
+
+	if len(keys) == 0 || len(nh.Caches) == 0 {
+		return histories
+	}
+
+	rawHistories, err := nh.Caches[0].MGetPipe(ctx, keys)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"error":     err.Error(),
+			"requestID": nh.RunID,
+		}).Error("perform checks: error reading node histories: " + err.Error())
+		return histories
+	}
+
+	for idx, rawHistory := range rawHistories {
+		var history pocket.NodeHistory
+		if err := cache.UnmarshallJSONResult(rawHistory, nil, &history); err != nil {
+			continue
+		}
+		histories[keys[idx]] = &history
+	}
+
+	return histories
+}
+
+// FilterPenalizedNodes removes the penalized nodes from the nodes that passed
+// the check, as long as the check's results are subject to sticky penalties
+func FilterPenalizedNodes(check pocket.Check, nodes []string, penalized map[string]bool, options *PerformChecksOptions) []string {
+	if len(penalized) == 0 || !check.CachePolicy(options.Session.Key).StickyPenalties {
+		return nodes
+	}
+
+	admitted := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if penalized[node] {
+			logger.Log.WithFields(log.Fields{
+				"requestID":    options.Ac.RequestID,
+				"blockchainID": options.Blockchain.ID,
+				"sessionKey":   options.Session.Key,
+				"serviceNode":  node,
+				"check":        check.Name(),
+			}).Warn("perform checks: node penalized by its check history: " + node)
+			continue
+		}
+		admitted = append(admitted, node)
+	}
+
+	return admitted
+}
@@ -0,0 +1,91 @@
+package base
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/Pocket/global-services/shared/cache"
+	"github.com/Pocket/global-services/shared/pocket"
+	"github.com/go-redis/redis/v8"
+	"github.com/pokt-foundation/pocket-go/provider"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+// SessionFreshness skips the sessions whose cached check results were written recently
+// and will outlive the next run, a sample of them is still checked to keep them honest
+type SessionFreshness struct {
+	Caches []*cache.Redis
+	// RecheckInterval is the least time between checks of the same session, disabled when zero
+	RecheckInterval time.Duration
+	// RunInterval is the time between runs, results expiring before the next run are re-checked
+	RunInterval time.Duration
+	// SampleRate is the fraction of the recently checked sessions that are checked anyway
+	SampleRate float64
+	RequestID  string
+
+	skipped int64
+	sampled int64
+}
+
+// ShouldCheck returns whether the checks have to be performed on the session, sessions are
+// checked whenever the cached results can't be read
+func (sf *SessionFreshness) ShouldCheck(ctx context.Context, session *provider.Session, checks []pocket.Check) bool {
+	if sf == nil || sf.RecheckInterval <= 0 || len(sf.Caches) == 0 || len(checks) == 0 {
+		return true
+	}
+
+	policies := make([]pocket.CachePolicy, 0, len(checks))
+	for _, check := range checks {
+		policies = append(policies, check.CachePolicy(session.Key))
+	}
+
+	values := make([]*redis.StringCmd, len(policies))
+	ttls := make([]*redis.DurationCmd, len(policies))
+	_, err := sf.Caches[0].Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for idx, policy := range policies {
+			values[idx] = pipe.Get(ctx, policy.Key)
+			ttls[idx] = pipe.TTL(ctx, policy.Key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		logger.Log.WithFields(log.Fields{
+			"requestID":  sf.RequestID,
+			"sessionKey": session.Key,
+			"error":      err.Error(),
+		}).Warn("session freshness: error reading cached results: " + err.Error())
+		return true
+	}
+
+	for idx, policy := range policies {
+		remainingTTL := ttls[idx].Val()
+		if remainingTTL < sf.RunInterval || !policy.CheckedWithin(values[idx].Val(), remainingTTL, sf.RecheckInterval) {
+			return true
+		}
+	}
+
+	if rand.Float64() < sf.SampleRate {
+		atomic.AddInt64(&sf.sampled, 1)
+		return true
+	}
+
+	atomic.AddInt64(&sf.skipped, 1)
+	return false
+}
+
+// LogSummary logs the amount of sessions skipped and sampled on the run
+func (sf *SessionFreshness) LogSummary() {
+	if sf == nil || sf.RecheckInterval <= 0 {
+		return
+	}
+
+	logger.Log.WithFields(log.Fields{
+		"requestID": sf.RequestID,
+		"skipped":   atomic.LoadInt64(&sf.skipped),
+		"sampled":   atomic.LoadInt64(&sf.sampled),
+	}).Info("session freshness: recently checked sessions")
+}
@@ -2,13 +2,13 @@ package base
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
+	dispatcher "github.com/Pocket/global-services/global-dispatcher"
+	reportdb "github.com/Pocket/global-services/global-dispatcher/database"
 	"github.com/Pocket/global-services/shared/cache"
 	"github.com/Pocket/global-services/shared/database"
 	dbclient "github.com/pokt-foundation/db-client/client"
@@ -26,7 +26,6 @@ import (
 
 var (
 	errMaxDispatchErrorsExceeded = errors.New("exceeded maximum allowance of dispatcher errors")
-	errLessThanMinimumNodes      = errors.New("there are less than the minimum session nodes found")
 
 	rpcURL                      = environment.MustGetString("RPC_URL")
 	dispatchURLs                = strings.Split(environment.MustGetString("DISPATCH_URLS"), ",")
@@ -35,18 +34,43 @@ var (
 	cacheTTL                    = environment.GetInt64("CACHE_TTL", 3600)
 	dispatchConcurrency         = environment.GetInt64("DISPATCH_CONCURRENCY", 200)
 	maxDispatchersErrorsAllowed = environment.GetInt64("MAX_DISPATCHER_ERRORS_ALLOWED", 2000)
+	maxConsecutiveErrors        = environment.GetInt64("MAX_DISPATCHER_CONSECUTIVE_ERRORS", 50)
+	dispatchAttempts            = environment.GetInt64("DISPATCH_ATTEMPTS", 2)
+	dispatchTimeout             = time.Duration(environment.GetInt64("DISPATCH_TIMEOUT", 0)) * time.Second
 	maxClientsCacheCheck        = environment.GetInt64("MAX_CLIENTS_CACHE_CHECK", 3)
 	cacheBatchSize              = environment.GetInt64("CACHE_BATCH_SIZE", 100)
+	cacheFreshnessPolicy        = environment.GetString("CACHE_FRESHNESS_POLICY", "all")
+	repairStaleCaches           = environment.GetBool("REPAIR_STALE_CACHES", true)
 	phdBaseURL                  = environment.MustGetString("PHD_BASE_URL")
 	phdAPIKey                   = environment.MustGetString("PHD_API_KEY")
+	reportsConnection           = environment.GetString("REPORTS_CONNECTION", "")
+	reportsTableName            = environment.GetString("REPORTS_TABLE_NAME", "dispatch_run_report")
 )
 
-// DispatchSessions obtains applications from the database, asserts they're staked
-// and dispatch the sessions of the chains from the applications, writing the results
-// to the cache clients provided while also  reporting any failure from the dispatchers.
-func DispatchSessions(ctx context.Context, requestID string) (uint32, error) {
+const (
+	minReportsPoolSize = 1
+	maxReportsPoolSize = 2
+)
+
+// Dispatcher holds the clients needed to dispatch sessions, so they can be
+// reused across several dispatch passes
+type Dispatcher struct {
+	DBClient *database.PostgresDBClient
+	Caches   []*cache.Redis
+	Stores   []gateway.SessionStore
+	Provider *provider.Provider
+	Policy   gateway.FreshnessPolicy
+	Priority *dispatchPriority
+	Reports  dispatcher.ReportStore
+
+	stagedMu sync.Mutex
+	staged   *stagedSessions
+}
+
+// NewDispatcher connects to the database, cache clients and rpc provider needed to dispatch sessions
+func NewDispatcher(ctx context.Context) (*Dispatcher, error) {
 	if len(redisConnectionStrings) <= 0 {
-		return 0, shared.ErrNoCacheClientProvided
+		return nil, shared.ErrNoCacheClientProvided
 	}
 
 	dbClient, err := database.NewPHDClient(dbclient.Config{
@@ -55,94 +79,209 @@ func DispatchSessions(ctx context.Context, requestID string) (uint32, error) {
 		Version: dbclient.V1,
 	})
 	if err != nil {
-		return 0, errors.New("error validating phd config: " + err.Error())
+		return nil, errors.New("error validating phd config: " + err.Error())
+	}
+
+	policy, err := gateway.ParseFreshnessPolicy(cacheFreshnessPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	priority, err := newDispatchPriority()
+	if err != nil {
+		return nil, err
 	}
 
 	caches, err := cache.ConnectToCacheClients(ctx, redisConnectionStrings, "", isRedisCluster)
 	if err != nil {
-		return 0, errors.New("error connecting to redis: " + err.Error())
+		return nil, errors.New("error connecting to redis: " + err.Error())
+	}
+
+	d := &Dispatcher{
+		DBClient: dbClient,
+		Caches:   caches,
+		Stores:   gateway.NewRedisSessionStores(caches),
+		Provider: provider.NewProvider(rpcURL, dispatchURLs),
+		Policy:   policy,
+		Priority: priority,
+	}
+
+	if reportsConnection != "" {
+		reports, err := reportdb.NewReportPostgresFromConnectionString(ctx, &database.PostgresOptions{
+			Connection:  reportsConnection,
+			MinPoolSize: minReportsPoolSize,
+			MaxPoolSize: maxReportsPoolSize,
+		}, reportsTableName)
+		if err != nil {
+			return nil, errors.New("error connecting to reports db: " + err.Error())
+		}
+		d.Reports = reports
 	}
 
-	rpcProvider := provider.NewProvider(rpcURL, dispatchURLs)
+	return d, nil
+}
+
+// Close closes all the cache and database connections of the dispatcher
+func (d *Dispatcher) Close() error {
+	if d.Reports != nil {
+		d.Reports.Close()
+	}
+	return cache.CloseConnections(d.Caches)
+}
 
-	blockHeight, err := rpcProvider.GetBlockHeight()
+// DispatchSessions obtains applications from the database, asserts they're staked
+// and dispatch the sessions of the chains from the applications, writing the results
+// to the cache clients provided while also  reporting any failure from the dispatchers.
+func DispatchSessions(ctx context.Context, requestID string) (*dispatcher.DispatchRunReport, error) {
+	d, err := NewDispatcher(ctx)
 	if err != nil {
-		return 0, errors.New("error obtaining block height: " + err.Error())
+		return nil, err
 	}
 
-	apps, _, err := dbClient.GetStakedApplications(ctx, rpcProvider)
+	blockHeight, err := d.Provider.GetBlockHeight()
 	if err != nil {
-		return 0, errors.New("error obtaining staked apps on db: " + err.Error())
+		return nil, errors.New("error obtaining block height: " + err.Error())
 	}
 
+	report, err := d.Dispatch(ctx, requestID, blockHeight)
+	if err != nil {
+		return report, err
+	}
+
+	err = d.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Dispatch performs a single dispatch pass over all the staked applications at the
+// given block height, only dispatching the sessions that are not up to date on the caches.
+// Returns the report of the run, which is also persisted if a report store is available.
+func (d *Dispatcher) Dispatch(ctx context.Context, requestID string, blockHeight int) (*dispatcher.DispatchRunReport, error) {
+	counter := &reportCounter{
+		report: &dispatcher.DispatchRunReport{
+			RequestID:   requestID,
+			StartedAt:   time.Now(),
+			BlockHeight: blockHeight,
+			Chains:      make(map[string]*dispatcher.ChainCounts),
+		},
+	}
+
+	apps, dbApps, err := d.DBClient.GetStakedApplications(ctx, d.Provider)
+	if err != nil {
+		return nil, errors.New("error obtaining staked apps on db: " + err.Error())
+	}
+
+	appChanges := d.diffStakedApps(ctx, requestID, apps)
+	items := d.getDispatchItems(ctx, requestID, apps, dbApps, appChanges)
+
+	// Dispatchers health is tracked per run so a failing dispatcher can recover on the next one
+	dispatcherPool := pocket.NewDispatcherPool(pocket.DispatcherPoolOptions{
+		RPCURL:               rpcURL,
+		DispatchURLs:         dispatchURLs,
+		MaxConsecutiveErrors: int(maxConsecutiveErrors),
+		MaxAttempts:          int(dispatchAttempts),
+		Timeout:              dispatchTimeout,
+	})
+
+	writeSessions := gateway.SessionBatchWriter(d.Stores)
+
 	var cacheWg sync.WaitGroup
 	cacheWg.Add(1)
 	cacheBatch := cache.BatchWriter(ctx, &cache.BatchWriterOptions{
-		Caches:    caches,
 		BatchSize: int(cacheBatchSize),
 		WaitGroup: &cacheWg,
 		RequestID: requestID,
+		Writer: func(ctx context.Context, items []*cache.Item) error {
+			err := writeSessions(ctx, items)
+
+			// Only the invalid items failed when the sessions were written
+			var invalidItemsErr *gateway.InvalidSessionItemsError
+			switch {
+			case errors.As(err, &invalidItemsErr):
+				counter.countCacheWriteFailures(len(invalidItemsErr.Keys))
+			case err != nil:
+				counter.countCacheWriteFailures(len(items))
+			}
+			return err
+		},
 	})
 
-	var failedDispatcherCalls uint32
+	var repairTTL time.Duration
+	if repairStaleCaches {
+		repairTTL = time.Duration(cacheTTL) * time.Second
+	}
+
 	var sem = semaphore.NewWeighted(dispatchConcurrency)
 	var wg sync.WaitGroup
 
-	for _, app := range apps {
-		for _, chain := range app.Chains {
-			sem.Acquire(ctx, 1)
-			wg.Add(1)
+	// Items are sorted by priority, so the most important sessions are acquired first
+	for idx, item := range items {
+		// The run ran out of time, the sessions left are dispatched on the next run
+		if err := sem.Acquire(ctx, 1); err != nil {
+			for _, notAttempted := range items[idx:] {
+				counter.count(notAttempted.chain, outcomeNotAttempted)
+			}
 
-			go func(publicKey, ch string) {
-				defer sem.Release(1)
-				defer wg.Done()
+			logger.Log.WithFields(log.Fields{
+				"notAttempted": len(items) - idx,
+				"error":        err.Error(),
+				"requestID":    requestID,
+			}).Warn("dispatch run stopped before dispatching all the sessions: " + err.Error())
+			break
+		}
+		wg.Add(1)
 
-				cacheKey := gateway.GetSessionCacheKey(publicKey, ch, "")
+		go func(publicKey, ch string) {
+			defer sem.Release(1)
+			defer wg.Done()
 
-				shouldDispatch, _ := gateway.ShouldDispatch(ctx, caches, blockHeight, cacheKey, int(maxClientsCacheCheck))
-				if !shouldDispatch {
-					return
-				}
+			cacheKey := gateway.GetSessionCacheKey(publicKey, ch, "")
 
-				dispatch, err := rpcProvider.Dispatch(publicKey, ch, nil)
-				if err != nil {
-					// Such sessions cannot be dispatched so not an actual error
-					if strings.Contains(err.Error(), errLessThanMinimumNodes.Error()) {
-						return
-					}
-
-					atomic.AddUint32(&failedDispatcherCalls, 1)
-					logger.Log.WithFields(log.Fields{
-						"appPublicKey": publicKey,
-						"chain":        ch,
-						"error":        err.Error(),
-						"requestID":    requestID,
-					}).Error("error dispatching: " + err.Error())
-					return
-				}
+			shouldDispatch, _ := gateway.ShouldDispatch(ctx, d.Stores, gateway.ShouldDispatchOptions{
+				BlockHeight: blockHeight,
+				Key:         cacheKey,
+				MaxClients:  int(maxClientsCacheCheck),
+				Policy:      d.Policy,
+				RepairTTL:   repairTTL,
+			})
+			if !shouldDispatch {
+				counter.count(ch, outcomeSkippedFresh)
+				return
+			}
 
-				session := pocket.NewSessionCamelCase(dispatch.Session)
-				// Embedding current block height within session so can be checked for cache
-				session.BlockHeight = dispatch.BlockHeight
-
-				marshalledSession, err := json.Marshal(session)
-				if err != nil {
-					logger.Log.WithFields(log.Fields{
-						"error":        err.Error(),
-						"requestID":    requestID,
-						"blockchainID": ch,
-						"sessionKey":   session.Key,
-					}).Errorf("sync check: error marshalling nodes: %s", err.Error())
+			dispatch, err := dispatcherPool.Dispatch(publicKey, ch, nil)
+			if err != nil {
+				// Such sessions cannot be dispatched so not an actual error
+				if pocket.IsLessThanMinimumNodesError(err) {
+					counter.count(ch, outcomeLessThanMinimumNodes)
 					return
 				}
 
-				cacheBatch <- &cache.Item{
-					Key:   cacheKey,
-					Value: marshalledSession,
-					TTL:   time.Duration(cacheTTL) * time.Second,
-				}
-			}(app.PublicKey, chain)
-		}
+				counter.count(ch, outcomeFailed)
+				logger.Log.WithFields(log.Fields{
+					"appPublicKey": publicKey,
+					"chain":        ch,
+					"error":        err.Error(),
+					"requestID":    requestID,
+				}).Error("error dispatching: " + err.Error())
+				return
+			}
+
+			counter.count(ch, outcomeDispatched)
+
+			session := pocket.NewSessionCamelCase(dispatch.Session)
+			// Embedding current block height within session so can be checked for cache
+			session.BlockHeight = dispatch.BlockHeight
+
+			cacheBatch <- &cache.Item{
+				Key:   cacheKey,
+				Value: session,
+				TTL:   time.Duration(cacheTTL) * time.Second,
+			}
+		}(item.publicKey, item.chain)
 	}
 
 	wg.Wait()
@@ -150,14 +289,17 @@ func DispatchSessions(ctx context.Context, requestID string) (uint32, error) {
 	close(cacheBatch)
 	cacheWg.Wait()
 
-	if failedDispatcherCalls > uint32(maxDispatchersErrorsAllowed) {
-		return failedDispatcherCalls, errMaxDispatchErrorsExceeded
-	}
+	report := counter.report
+	report.AppsConsidered = len(apps)
+	report.Dispatchers = dispatcherPool.Stats()
+	report.AppChanges = appChanges
+	report.DurationSeconds = time.Since(report.StartedAt).Seconds()
 
-	err = cache.CloseConnections(caches)
-	if err != nil {
-		return 0, err
+	d.saveReport(ctx, report)
+
+	if report.FailedDispatcherCalls > uint32(maxDispatchersErrorsAllowed) {
+		return report, errMaxDispatchErrorsExceeded
 	}
 
-	return failedDispatcherCalls, nil
+	return report, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	dispatcher "github.com/Pocket/global-services/global-dispatcher"
 	base "github.com/Pocket/global-services/global-dispatcher/cmd/dispatch"
 	"github.com/Pocket/global-services/shared/environment"
 	"github.com/Pocket/global-services/shared/utils"
@@ -19,16 +20,21 @@ func main() {
 	defer cancel()
 
 	requestID, _ := utils.RandomHex(32)
-	failedDispatcherCalls, err := base.DispatchSessions(ctx, requestID)
+	report, err := base.DispatchSessions(ctx, requestID)
+	if report == nil {
+		report = &dispatcher.DispatchRunReport{}
+	}
 	if err != nil {
 		logger.Log.WithFields(log.Fields{
 			"requestID":      requestID,
 			"error":          err.Error(),
-			"failedDispatch": failedDispatcherCalls,
+			"failedDispatch": report.FailedDispatcherCalls,
+			"report":         report,
 		}).Error("ERROR DISPATCHING SESSION: " + err.Error())
 	}
 	logger.Log.WithFields(log.Fields{
 		"requestID":      requestID,
-		"failedDispatch": failedDispatcherCalls,
+		"failedDispatch": report.FailedDispatcherCalls,
+		"report":         report,
 	}).Info("GLOBAL DISPATCHER RESULT")
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	base "github.com/Pocket/global-services/global-dispatcher/cmd/dispatch"
+	"github.com/Pocket/global-services/shared/environment"
+	"github.com/Pocket/global-services/shared/pocket"
+	"github.com/Pocket/global-services/shared/utils"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	timeout      = time.Duration(environment.GetInt64("TIMEOUT", 360)) * time.Second
+	pollInterval = time.Duration(environment.GetInt64("BLOCK_POLL_INTERVAL", 10)) * time.Second
+	blockTime    = time.Duration(environment.GetInt64("BLOCK_TIME", 900)) * time.Second
+	// prewarmEnabled stages all the sessions of a new session height and promotes them at once
+	// before the regular dispatch pass, so the gateway never reads a mix of old and new sessions
+	prewarmEnabled = environment.GetBool("PREWARM_ENABLED", true)
+)
+
+// The daemon keeps all the connections of the dispatcher open and only performs a
+// dispatch pass once the network crosses into a new session
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dispatcher, err := base.NewDispatcher(ctx)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Fatal("ERROR STARTING DISPATCHER DAEMON: " + err.Error())
+	}
+	defer dispatcher.Close()
+
+	blocksPerSession, err := pocket.GetBlocksPerSession(dispatcher.Provider)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Fatal("ERROR OBTAINING BLOCKS PER SESSION: " + err.Error())
+	}
+
+	// Staged sessions are only useful until the session they belong to ends
+	stagedTTL := time.Duration(blocksPerSession) * blockTime
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	lastSessionHeight := 0
+	stagedSessionHeight := 0
+	for {
+		blockHeight, err := dispatcher.Provider.GetBlockHeight()
+		if err != nil {
+			logger.Log.WithFields(log.Fields{
+				"error": err.Error(),
+			}).Error("error obtaining block height: " + err.Error())
+		}
+
+		sessionHeight := pocket.GetSessionHeight(blockHeight, blocksPerSession)
+		if err == nil && sessionHeight != lastSessionHeight {
+			// The boundary block just landed, so its session height is now valid to dispatch
+			if prewarmEnabled && sessionHeight != stagedSessionHeight &&
+				prewarm(ctx, dispatcher, sessionHeight, stagedTTL) {
+				stagedSessionHeight = sessionHeight
+				promote(ctx, dispatcher, sessionHeight, blockHeight)
+			}
+
+			if dispatch(ctx, dispatcher, blockHeight) {
+				lastSessionHeight = sessionHeight
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch runs a single dispatch pass and returns whether it succeeded
+func dispatch(ctx context.Context, dispatcher *base.Dispatcher, blockHeight int) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	requestID, _ := utils.RandomHex(32)
+	report, err := dispatcher.Dispatch(ctx, requestID, blockHeight)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"requestID":   requestID,
+			"blockHeight": blockHeight,
+			"error":       err.Error(),
+			"report":      report,
+		}).Error("ERROR DISPATCHING SESSION: " + err.Error())
+		return false
+	}
+
+	logger.Log.WithFields(log.Fields{
+		"requestID":      requestID,
+		"blockHeight":    blockHeight,
+		"failedDispatch": report.FailedDispatcherCalls,
+		"report":         report,
+	}).Info("GLOBAL DISPATCHER RESULT")
+
+	return true
+}
+
+// prewarm stages the sessions of the session height and returns whether it succeeded
+func prewarm(ctx context.Context, dispatcher *base.Dispatcher, sessionHeight int, stagedTTL time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	requestID, _ := utils.RandomHex(32)
+	result, err := dispatcher.Prewarm(ctx, requestID, sessionHeight, stagedTTL)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"requestID":     requestID,
+			"sessionHeight": sessionHeight,
+			"error":         err.Error(),
+		}).Error("ERROR PREWARMING SESSION: " + err.Error())
+		return false
+	}
+
+	logger.Log.WithFields(log.Fields{
+		"requestID":     requestID,
+		"sessionHeight": sessionHeight,
+		"staged":        result.Staged,
+		"failed":        result.Failed,
+	}).Info("GLOBAL DISPATCHER PREWARM RESULT")
+
+	return true
+}
+
+// promote replaces the cached sessions with the ones staged for the session height, if any
+func promote(ctx context.Context, dispatcher *base.Dispatcher, sessionHeight, blockHeight int) {
+	promoted, err := dispatcher.PromoteSessions(ctx, sessionHeight, blockHeight)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"sessionHeight": sessionHeight,
+			"promoted":      promoted,
+			"error":         err.Error(),
+		}).Error("ERROR PROMOTING STAGED SESSIONS: " + err.Error())
+		return
+	}
+
+	if promoted > 0 {
+		logger.Log.WithFields(log.Fields{
+			"sessionHeight": sessionHeight,
+			"promoted":      promoted,
+		}).Info("promoted staged sessions")
+	}
+}
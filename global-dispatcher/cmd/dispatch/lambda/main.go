@@ -18,18 +18,20 @@ import (
 func LambdaHandler(ctx context.Context) (events.APIGatewayProxyResponse, error) {
 	lc, _ := lambdacontext.FromContext(ctx)
 
-	failedDispatcherCalls, err := base.DispatchSessions(ctx, lc.AwsRequestID)
+	report, err := base.DispatchSessions(ctx, lc.AwsRequestID)
 	if err != nil {
 		logger.Log.WithFields(log.Fields{
 			"requestID": lc.AwsRequestID,
 			"error":     err.Error(),
+			"report":    report,
 		}).Error("ERROR DISPATCHING SESSION: " + err.Error())
 		return *apigateway.NewErrorResponse(http.StatusInternalServerError, err), err
 	}
 
 	result := map[string]interface{}{
 		"ok":                    true,
-		"failedDispatcherCalls": failedDispatcherCalls,
+		"failedDispatcherCalls": report.FailedDispatcherCalls,
+		"report":                report,
 	}
 
 	// Internal logging
@@ -0,0 +1,165 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Pocket/global-services/shared/cache"
+	"github.com/Pocket/global-services/shared/gateway"
+	"github.com/Pocket/global-services/shared/pocket"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"golang.org/x/sync/semaphore"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+// stagedSessions are the sessions staged for a session height before being promoted,
+// keyed by their staged key with the session key as value
+type stagedSessions struct {
+	sessionHeight int
+	keys          map[string]string
+}
+
+// PrewarmResult is the result of staging the sessions of a session height
+type PrewarmResult struct {
+	SessionHeight int `json:"sessionHeight"`
+	Staged        int `json:"staged"`
+	Failed        int `json:"failed"`
+}
+
+// Prewarm dispatches the sessions of all the staked applications for the given session height
+// and stages them on the stores under versioned keys, so they can all be promoted at once with
+// PromoteSessions. Nodes only return sessions whose first block exists, so the session height
+// must be the one of the boundary block that just landed. Staged sessions expire after the
+// given ttl, which is meant to be about a session long.
+func (d *Dispatcher) Prewarm(ctx context.Context, requestID string, sessionHeight int, stagedTTL time.Duration) (*PrewarmResult, error) {
+	apps, dbApps, err := d.DBClient.GetStakedApplications(ctx, d.Provider)
+	if err != nil {
+		return nil, errors.New("error obtaining staked apps on db: " + err.Error())
+	}
+
+	items := d.getDispatchItems(ctx, requestID, apps, dbApps, nil)
+
+	dispatcherPool := pocket.NewDispatcherPool(pocket.DispatcherPoolOptions{
+		RPCURL:               rpcURL,
+		DispatchURLs:         dispatchURLs,
+		MaxConsecutiveErrors: int(maxConsecutiveErrors),
+		MaxAttempts:          int(dispatchAttempts),
+		Timeout:              dispatchTimeout,
+	})
+
+	var cacheWg sync.WaitGroup
+	cacheWg.Add(1)
+	cacheBatch := cache.BatchWriter(ctx, &cache.BatchWriterOptions{
+		BatchSize: int(cacheBatchSize),
+		WaitGroup: &cacheWg,
+		RequestID: requestID,
+		Writer:    gateway.SessionBatchWriter(d.Stores),
+	})
+
+	result := &PrewarmResult{SessionHeight: sessionHeight}
+	staged := &stagedSessions{
+		sessionHeight: sessionHeight,
+		keys:          make(map[string]string),
+	}
+
+	var mu sync.Mutex
+	var sem = semaphore.NewWeighted(dispatchConcurrency)
+	var wg sync.WaitGroup
+
+	for idx, item := range items {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			mu.Lock()
+			result.Failed += len(items) - idx
+			mu.Unlock()
+
+			logger.Log.WithFields(log.Fields{
+				"sessionHeight": sessionHeight,
+				"notAttempted":  len(items) - idx,
+				"error":         err.Error(),
+				"requestID":     requestID,
+			}).Warn("prewarm stopped before dispatching all the sessions: " + err.Error())
+			break
+		}
+		wg.Add(1)
+
+		go func(publicKey, ch string) {
+			defer sem.Release(1)
+			defer wg.Done()
+
+			dispatch, err := dispatcherPool.Dispatch(publicKey, ch, &provider.DispatchRequestOptions{
+				Height: sessionHeight,
+			})
+			if err == nil && (dispatch.Session == nil || dispatch.Session.Header == nil ||
+				dispatch.Session.Header.SessionHeight != sessionHeight) {
+				err = errors.New("dispatcher did not return the session of the session height")
+			}
+			if err != nil {
+				mu.Lock()
+				result.Failed++
+				mu.Unlock()
+
+				if !pocket.IsLessThanMinimumNodesError(err) {
+					logger.Log.WithFields(log.Fields{
+						"appPublicKey":  publicKey,
+						"chain":         ch,
+						"sessionHeight": sessionHeight,
+						"error":         err.Error(),
+						"requestID":     requestID,
+					}).Warn("error prewarming session: " + err.Error())
+				}
+				return
+			}
+
+			// The block height of the session is stamped once it's promoted
+			session := pocket.NewSessionCamelCase(dispatch.Session)
+
+			stagedKey := gateway.GetStagedSessionCacheKey(publicKey, ch, sessionHeight)
+
+			mu.Lock()
+			result.Staged++
+			staged.keys[stagedKey] = gateway.GetSessionCacheKey(publicKey, ch, "")
+			mu.Unlock()
+
+			cacheBatch <- &cache.Item{
+				Key:   stagedKey,
+				Value: session,
+				TTL:   stagedTTL,
+			}
+		}(item.publicKey, item.chain)
+	}
+
+	wg.Wait()
+
+	close(cacheBatch)
+	cacheWg.Wait()
+
+	d.stagedMu.Lock()
+	d.staged = staged
+	d.stagedMu.Unlock()
+
+	return result, nil
+}
+
+// PromoteSessions replaces the sessions on the stores with the ones staged for the given
+// session height, marked as seen at the block height observed. Returns the amount of
+// sessions promoted on all the stores.
+func (d *Dispatcher) PromoteSessions(ctx context.Context, sessionHeight, blockHeight int) (int, error) {
+	d.stagedMu.Lock()
+	staged := d.staged
+	d.stagedMu.Unlock()
+
+	if staged == nil || staged.sessionHeight != sessionHeight || len(staged.keys) == 0 {
+		return 0, nil
+	}
+
+	promoted, err := gateway.PromoteStagedSessions(ctx, d.Stores, staged.keys, sessionHeight, blockHeight, time.Duration(cacheTTL)*time.Second)
+	if err != nil {
+		return promoted, errors.New("error promoting staged sessions: " + err.Error())
+	}
+
+	return promoted, nil
+}
@@ -0,0 +1,141 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Pocket/global-services/shared/database"
+	"github.com/Pocket/global-services/shared/gateway"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-foundation/portal-db/types"
+	"github.com/pokt-foundation/utils-go/environment"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	chainPriorityWeights = environment.GetString("CHAIN_PRIORITY_WEIGHTS", "")
+	// highPriorityPlans are the PHD pay plans whose apps are dispatched before the rest of their chain
+	highPriorityPlans = strings.Split(environment.GetString("HIGH_PRIORITY_PLANS", "ENTERPRISE"), ",")
+)
+
+// dispatchPriority is the configuration used to decide the order sessions are dispatched
+type dispatchPriority struct {
+	// chainWeights are the weights of the chains, higher weights are dispatched first
+	chainWeights map[string]int
+	// highPriorityPlans are the PHD pay plans of the applications dispatched before the rest of their chain
+	highPriorityPlans map[string]bool
+}
+
+// dispatchItem is the session of a single app and chain to be dispatched
+type dispatchItem struct {
+	publicKey    string
+	chain        string
+	chainWeight  int
+	highPriority bool
+	changed      bool
+	// cachedSessionHeight is zero when the session is not cached
+	cachedSessionHeight int
+	// maxRelays are the relays per session the app staked for, used as its relay volume
+	maxRelays int64
+}
+
+// newDispatchPriority parses the priority configuration, CHAIN_PRIORITY_WEIGHTS must
+// be a JSON object of chain IDs and their weight, i.e {"0021": 10, "0001": 5}
+func newDispatchPriority() (*dispatchPriority, error) {
+	priority := &dispatchPriority{
+		chainWeights:      make(map[string]int),
+		highPriorityPlans: make(map[string]bool),
+	}
+
+	if chainPriorityWeights != "" {
+		if err := json.Unmarshal([]byte(chainPriorityWeights), &priority.chainWeights); err != nil {
+			return nil, errors.New("error parsing chain priority weights: " + err.Error())
+		}
+	}
+
+	for _, plan := range highPriorityPlans {
+		if plan = strings.TrimSpace(plan); plan != "" {
+			priority.highPriorityPlans[plan] = true
+		}
+	}
+
+	return priority, nil
+}
+
+// getDispatchItems returns the sessions of all the staked apps sorted by the order
+// in which they should be dispatched:
+//  1. Chains with the highest weight
+//  2. Applications whose PHD pay plan is a high priority one
+//  3. Applications newly staked or that changed chains since the last run
+//  4. Sessions not cached or whose cached session is the oldest, thus closest to expire
+//  5. Applications with the highest relay volume
+func (d *Dispatcher) getDispatchItems(ctx context.Context, requestID string, apps []*provider.App, dbApps []*types.Application, changes []*database.AppChange) []*dispatchItem {
+	changedApps := make(map[string]bool)
+	for _, change := range changes {
+		if change.Type == database.AppStaked || change.Type == database.AppChainsChanged {
+			changedApps[change.PublicKey] = true
+		}
+	}
+
+	items := []*dispatchItem{}
+	keys := []string{}
+	for idx, app := range apps {
+		highPriority := idx < len(dbApps) && d.Priority.highPriorityPlans[string(dbApps[idx].Limit.PayPlan.Type)]
+		maxRelays, _ := strconv.ParseInt(app.MaxRelays, 10, 64)
+
+		for _, chain := range app.Chains {
+			items = append(items, &dispatchItem{
+				publicKey:    app.PublicKey,
+				chain:        chain,
+				chainWeight:  d.Priority.chainWeights[chain],
+				highPriority: highPriority,
+				changed:      changedApps[app.PublicKey],
+				maxRelays:    maxRelays,
+			})
+			keys = append(keys, gateway.GetSessionCacheKey(app.PublicKey, chain, ""))
+		}
+	}
+
+	if len(d.Stores) > 0 {
+		// A single store is enough to estimate how close to expire the sessions are
+		sessions, err := d.Stores[0].MultiGet(ctx, keys)
+		if err != nil {
+			logger.Log.WithFields(log.Fields{
+				"requestID": requestID,
+				"error":     err.Error(),
+			}).Warn("error obtaining cached sessions for priority: " + err.Error())
+		}
+		for idx, session := range sessions {
+			if session != nil && session.Header != nil {
+				items[idx].cachedSessionHeight = session.Header.SessionHeight
+			}
+		}
+	}
+
+	sortDispatchItems(items)
+
+	return items
+}
+
+func sortDispatchItems(items []*dispatchItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		switch {
+		case a.chainWeight != b.chainWeight:
+			return a.chainWeight > b.chainWeight
+		case a.highPriority != b.highPriority:
+			return a.highPriority
+		case a.changed != b.changed:
+			return a.changed
+		case a.cachedSessionHeight != b.cachedSessionHeight:
+			return a.cachedSessionHeight < b.cachedSessionHeight
+		}
+		return a.maxRelays > b.maxRelays
+	})
+}
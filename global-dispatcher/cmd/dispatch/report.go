@@ -0,0 +1,79 @@
+package base
+
+import (
+	"context"
+	"sync"
+
+	dispatcher "github.com/Pocket/global-services/global-dispatcher"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+type sessionOutcome int
+
+const (
+	outcomeSkippedFresh sessionOutcome = iota
+	outcomeDispatched
+	outcomeLessThanMinimumNodes
+	outcomeFailed
+	outcomeNotAttempted
+)
+
+// reportCounter keeps the counts of a dispatch run report, safe for concurrent use
+type reportCounter struct {
+	mu     sync.Mutex
+	report *dispatcher.DispatchRunReport
+}
+
+// count adds the outcome of a session to the run totals and to the ones of its chain
+func (rc *reportCounter) count(chain string, outcome sessionOutcome) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	chainCounts, ok := rc.report.Chains[chain]
+	if !ok {
+		chainCounts = &dispatcher.ChainCounts{}
+		rc.report.Chains[chain] = chainCounts
+	}
+
+	switch outcome {
+	case outcomeSkippedFresh:
+		rc.report.SessionsSkippedFresh++
+		chainCounts.SkippedFresh++
+	case outcomeDispatched:
+		rc.report.SessionsDispatched++
+		chainCounts.Dispatched++
+	case outcomeLessThanMinimumNodes:
+		rc.report.LessThanMinimumNodes++
+		chainCounts.LessThanMinimumNodes++
+	case outcomeFailed:
+		rc.report.FailedDispatcherCalls++
+		chainCounts.Failed++
+	case outcomeNotAttempted:
+		rc.report.SessionsNotAttempted++
+		chainCounts.NotAttempted++
+	}
+}
+
+// countCacheWriteFailures adds the amount of sessions that couldn't be written to the caches
+func (rc *reportCounter) countCacheWriteFailures(failures int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.report.CacheWriteFailures += uint32(failures)
+}
+
+// saveReport persists the report of the run if a report store is configured
+func (d *Dispatcher) saveReport(ctx context.Context, report *dispatcher.DispatchRunReport) {
+	if d.Reports == nil {
+		return
+	}
+
+	if err := d.Reports.CreateReport(ctx, report); err != nil {
+		logger.Log.WithFields(log.Fields{
+			"requestID": report.RequestID,
+			"error":     err.Error(),
+		}).Error("error saving dispatch run report: " + err.Error())
+	}
+}
@@ -0,0 +1,72 @@
+package base
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Pocket/global-services/shared/cache"
+	"github.com/Pocket/global-services/shared/database"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-foundation/utils-go/environment"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	appsSnapshotKey = environment.GetString("APPS_SNAPSHOT_KEY", "staked-apps-snapshot")
+	appsSnapshotTTL = environment.GetInt64("APPS_SNAPSHOT_TTL", 86400)
+)
+
+// diffStakedApps compares the staked apps against the snapshot saved by the previous
+// run, logging every change found and saving the current snapshot for the next run
+func (d *Dispatcher) diffStakedApps(ctx context.Context, requestID string, apps []*provider.App) []*database.AppChange {
+	current := database.NewAppsSnapshot(apps)
+	defer func() {
+		if err := cache.WriteJSONToCaches(ctx, d.Caches, appsSnapshotKey, current, uint(appsSnapshotTTL)); err != nil {
+			logger.Log.WithFields(log.Fields{
+				"requestID": requestID,
+				"error":     err.Error(),
+			}).Error("error saving staked apps snapshot: " + err.Error())
+		}
+	}()
+
+	previous, err := d.getAppsSnapshot(ctx)
+	if err != nil {
+		// Without a previous snapshot every app would be reported as newly staked
+		logger.Log.WithFields(log.Fields{
+			"requestID": requestID,
+			"error":     err.Error(),
+		}).Warn("no previous staked apps snapshot: " + err.Error())
+		return nil
+	}
+
+	changes := database.DiffAppsSnapshots(previous, current)
+	for _, change := range changes {
+		logger.Log.WithFields(log.Fields{
+			"requestID":      requestID,
+			"appPublicKey":   change.PublicKey,
+			"chains":         change.Chains,
+			"previousChains": change.PreviousChains,
+			"changeType":     change.Type,
+		}).Info("STAKED APP CHANGE: " + string(change.Type))
+	}
+
+	return changes
+}
+
+// getAppsSnapshot returns the staked apps snapshot from the first cache that has it
+func (d *Dispatcher) getAppsSnapshot(ctx context.Context) (database.AppsSnapshot, error) {
+	err := errors.New("no cache clients available")
+
+	for _, cl := range d.Caches {
+		rawSnapshot, getErr := cl.Client.Get(ctx, cl.KeyPrefix+appsSnapshotKey).Result()
+
+		var snapshot database.AppsSnapshot
+		if err = cache.UnmarshallJSONResult(rawSnapshot, getErr, &snapshot); err == nil {
+			return snapshot, nil
+		}
+	}
+
+	return nil, err
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	dispatcher "github.com/Pocket/global-services/global-dispatcher"
+
+	"github.com/Pocket/global-services/shared/database"
+)
+
+var (
+	// ErrEmptyReportTableName when report table name is missing
+	ErrEmptyReportTableName = errors.New("report table name is empty")
+)
+
+// ReportPostgres is an interface to operations in the dispatch run reports database
+type ReportPostgres struct {
+	Db              *database.Postgres
+	ReportTableName string
+}
+
+// NewReportPostgresFromConnectionString returns a dispatch run report postgres store from a connection string
+func NewReportPostgresFromConnectionString(ctx context.Context, options *database.PostgresOptions, reportTableName string) (*ReportPostgres, error) {
+	if reportTableName == "" {
+		return nil, ErrEmptyReportTableName
+	}
+
+	db, err := database.NewPostgresDatabase(ctx, options)
+	if err != nil {
+		return nil, errors.New("unable to connect to postgres db: " + err.Error())
+	}
+
+	return &ReportPostgres{
+		Db:              db,
+		ReportTableName: reportTableName,
+	}, nil
+}
+
+// CreateReport saves a new dispatch run report
+func (r *ReportPostgres) CreateReport(ctx context.Context, report *dispatcher.DispatchRunReport) error {
+	chains, err := json.Marshal(report.Chains)
+	if err != nil {
+		return err
+	}
+	dispatchers, err := json.Marshal(report.Dispatchers)
+	if err != nil {
+		return err
+	}
+	appChanges, err := json.Marshal(report.AppChanges)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Db.Conn.Exec(ctx, fmt.Sprintf(`
+	INSERT INTO
+	 %s
+	 (request_id,
+		started_at,
+		block_height,
+		duration_seconds,
+		apps_considered,
+		sessions_skipped_fresh,
+		sessions_dispatched,
+		less_than_minimum_nodes,
+		failed_dispatcher_calls,
+		cache_write_failures,
+		sessions_not_attempted,
+		chains,
+		dispatchers,
+		app_changes
+		)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)`, r.ReportTableName),
+		report.RequestID,
+		report.StartedAt,
+		report.BlockHeight,
+		report.DurationSeconds,
+		report.AppsConsidered,
+		report.SessionsSkippedFresh,
+		report.SessionsDispatched,
+		report.LessThanMinimumNodes,
+		report.FailedDispatcherCalls,
+		report.CacheWriteFailures,
+		report.SessionsNotAttempted,
+		chains,
+		dispatchers,
+		appChanges)
+
+	return err
+}
+
+// Close closes the postgres connection of the store
+func (r *ReportPostgres) Close() {
+	r.Db.Conn.Close()
+}
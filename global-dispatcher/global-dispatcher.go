@@ -0,0 +1,42 @@
+package dispatcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/Pocket/global-services/shared/database"
+	"github.com/Pocket/global-services/shared/pocket"
+)
+
+// ChainCounts model of the outcome of the sessions of a single chain within a dispatch run
+type ChainCounts struct {
+	SkippedFresh         uint32 `json:"skippedFresh"`
+	Dispatched           uint32 `json:"dispatched"`
+	LessThanMinimumNodes uint32 `json:"lessThanMinimumNodes"`
+	Failed               uint32 `json:"failed"`
+	NotAttempted         uint32 `json:"notAttempted"`
+}
+
+// DispatchRunReport model of the outcome of a single dispatch run
+type DispatchRunReport struct {
+	RequestID             string                    `json:"requestID"`
+	StartedAt             time.Time                 `json:"startedAt"`
+	BlockHeight           int                       `json:"blockHeight"`
+	DurationSeconds       float64                   `json:"durationSeconds"`
+	AppsConsidered        int                       `json:"appsConsidered"`
+	SessionsSkippedFresh  uint32                    `json:"sessionsSkippedFresh"`
+	SessionsDispatched    uint32                    `json:"sessionsDispatched"`
+	LessThanMinimumNodes  uint32                    `json:"lessThanMinimumNodes"`
+	FailedDispatcherCalls uint32                    `json:"failedDispatcherCalls"`
+	CacheWriteFailures    uint32                    `json:"cacheWriteFailures"`
+	SessionsNotAttempted  uint32                    `json:"sessionsNotAttempted"`
+	Chains                map[string]*ChainCounts   `json:"chains"`
+	Dispatchers           []*pocket.DispatcherStats `json:"dispatchers"`
+	AppChanges            []*database.AppChange     `json:"appChanges"`
+}
+
+// ReportStore is the interface for all the operations on the dispatch run reports
+type ReportStore interface {
+	CreateReport(ctx context.Context, report *DispatchRunReport) error
+	Close()
+}
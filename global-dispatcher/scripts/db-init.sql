@@ -0,0 +1,20 @@
+-- Tables
+CREATE TABLE IF NOT EXISTS dispatch_run_report (
+  request_id VARCHAR(64) PRIMARY KEY,
+  started_at TIMESTAMP WITH TIME ZONE,
+  block_height INT,
+  duration_seconds REAL,
+  apps_considered INT,
+  sessions_skipped_fresh INT,
+  sessions_dispatched INT,
+  less_than_minimum_nodes INT,
+  failed_dispatcher_calls INT,
+  cache_write_failures INT,
+  sessions_not_attempted INT,
+  chains JSONB,
+  dispatchers JSONB,
+  app_changes JSONB
+);
+ALTER TABLE dispatch_run_report ADD COLUMN IF NOT EXISTS sessions_not_attempted INT;
+-- Indexes
+CREATE INDEX IF NOT EXISTS started_at_idx ON dispatch_run_report (started_at);
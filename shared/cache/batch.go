@@ -22,12 +22,17 @@ type Item struct {
 type BatchWriterOptions struct {
 	Caches    []*Redis
 	BatchSize int
-	WaitGroup *sync.WaitGroup
-	RequestID string
+	// FlushInterval is the longest an item waits to be written when the batch isn't full, no limit when zero
+	FlushInterval time.Duration
+	WaitGroup     *sync.WaitGroup
+	RequestID     string
+	// Writer replaces the default write of the batch to all the caches when given
+	Writer func(ctx context.Context, items []*Item) error
 }
 
 // BatchWriter spans a monitor goroutine which is constantly checking for items to write to redis,
 // once the items reached the minimum threshold it is sent to be written as a single Redis SET operation.
+// The wait group is done once the channel returned is closed and all the items were written.
 func BatchWriter(ctx context.Context, options *BatchWriterOptions) chan *Item {
 	batch := make(chan *Item, options.BatchSize)
 	go monitorBatch(ctx, batch, *options)
@@ -36,36 +41,51 @@ func BatchWriter(ctx context.Context, options *BatchWriterOptions) chan *Item {
 
 func monitorBatch(ctx context.Context, batch chan *Item, options BatchWriterOptions) {
 	defer options.WaitGroup.Done()
-	items := []*Item{}
 
-	for {
-		item, ok := <-batch
-		if item != nil {
-			items = append(items, item)
-		}
+	batcher := utils.NewBatcher(ctx, utils.BatcherOptions{
+		MaxSize: options.BatchSize,
+		MaxWait: options.FlushInterval,
+	}, func(ctx context.Context, items []*Item) {
+		writeBatch(ctx, items, options)
+	})
 
-		if ok && len(items) < options.BatchSize {
+	dropped := 0
+	for item := range batch {
+		if item == nil {
 			continue
 		}
-		writeBatch(ctx, items, options.Caches, options.RequestID)
-		items = nil
-
-		if !ok {
-			break
+		// Keep draining the channel once the context is done so the senders never block
+		if err := batcher.Add(item); err != nil {
+			dropped++
 		}
 	}
+	batcher.Close()
+
+	if dropped > 0 {
+		logger.Log.WithFields(log.Fields{
+			"requestID": options.RequestID,
+			"dropped":   dropped,
+		}).Warn("cache: items dropped after the context was done")
+	}
 }
 
-func writeBatch(ctx context.Context, items []*Item, caches []*Redis, requestID string) {
-	if err := utils.RunFnOnSliceSingleFailure(caches, func(cache *Redis) error {
-		_, err := cache.PipeOperation(ctx, items, func(pipe redis.Pipeliner, it *Item) error {
-			return pipe.Set(ctx, it.Key, it.Value, it.TTL).Err()
-		})
-		return err
-	}); err != nil {
+func writeBatch(ctx context.Context, items []*Item, options BatchWriterOptions) {
+	write := options.Writer
+	if write == nil {
+		write = func(ctx context.Context, items []*Item) error {
+			return utils.RunFnOnSliceSingleFailure(options.Caches, func(cache *Redis) error {
+				_, err := cache.PipeOperation(ctx, items, func(pipe redis.Pipeliner, it *Item) error {
+					return pipe.Set(ctx, it.Key, it.Value, it.TTL).Err()
+				})
+				return err
+			})
+		}
+	}
+
+	if err := write(ctx, items); err != nil {
 		logger.Log.WithFields(log.Fields{
 			"error":     err.Error(),
-			"requestID": requestID,
+			"requestID": options.RequestID,
 		}).Errorf("cache: error writing cache batch: %s", err.Error())
 	}
 }
@@ -10,6 +10,8 @@ import (
 	"golang.org/x/exp/slices"
 )
 
+const appsPerPage = 3000
+
 // PostgresDBClient holds the phd client lib and additional methods for filtering applications
 type PostgresDBClient struct {
 	dbclient.IDBReader
@@ -54,10 +56,7 @@ func FilterStakedAppsNotOnDB(dbApps []*types.Application, pocket *provider.Provi
 	var stakedApps []*provider.App
 	var stakedAppsDB []*types.Application
 
-	networkApps, err := pocket.GetApps(&provider.GetAppsOptions{
-		PerPage: 3000,
-		Page:    1,
-	})
+	networkApps, err := GetNetworkApps(pocket)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -66,7 +65,7 @@ func FilterStakedAppsNotOnDB(dbApps []*types.Application, pocket *provider.Provi
 		return app.GatewayAAT.ApplicationPublicKey
 	})
 
-	for _, ntApp := range networkApps.Result {
+	for _, ntApp := range networkApps {
 		if _, ok := publicKeyToApps[ntApp.PublicKey]; ok {
 			stakedApps = append(stakedApps, ntApp)
 			stakedAppsDB = append(stakedAppsDB, publicKeyToApps[ntApp.PublicKey])
@@ -75,6 +74,28 @@ func FilterStakedAppsNotOnDB(dbApps []*types.Application, pocket *provider.Provi
 	return stakedApps, stakedAppsDB, nil
 }
 
+// GetNetworkApps returns all the applications on the network, going through all the pages
+func GetNetworkApps(pocket *provider.Provider) ([]*provider.App, error) {
+	var networkApps []*provider.App
+
+	for page := 1; ; page++ {
+		apps, err := pocket.GetApps(&provider.GetAppsOptions{
+			PerPage: appsPerPage,
+			Page:    page,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		networkApps = append(networkApps, apps.Result...)
+		if page >= apps.TotalPages || len(apps.Result) == 0 {
+			break
+		}
+	}
+
+	return networkApps, nil
+}
+
 // filter calls a function on each element of a slice, returning a new slice whose elements returned true from the function
 func filter[T any](slice []T, f func(T) bool) []T {
 	var n []T
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
@@ -80,3 +81,33 @@ func TestCache_GetAppsFromList(t *testing.T) {
 	c.Equal(len(apps), 1)
 	c.Equal(apps[0].ID, appID)
 }
+
+func TestCache_GetNetworkAppsPaginated(t *testing.T) {
+	c := require.New(t)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodPost, fmt.Sprintf("%s%s", testNetworkURL, networkAppsEndpoint),
+		func(req *http.Request) (*http.Response, error) {
+			var body struct {
+				Opts struct {
+					Page int `json:"page"`
+				} `json:"opts"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+
+			return httpmock.NewStringResponse(http.StatusOK, fmt.Sprintf(
+				`{"result":[{"public_key":"app_%d","chains":["0021"]}],"page":%d,"total_pages":2}`,
+				body.Opts.Page, body.Opts.Page)), nil
+		})
+
+	apps, err := GetNetworkApps(provider.NewProvider(testNetworkURL, []string{testNetworkURL}))
+	c.NoError(err)
+
+	c.Len(apps, 2)
+	c.Equal("app_1", apps[0].PublicKey)
+	c.Equal("app_2", apps[1].PublicKey)
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"sort"
+
+	"github.com/pokt-foundation/pocket-go/provider"
+	"golang.org/x/exp/slices"
+)
+
+// AppChangeType is the kind of change of a staked application between two snapshots
+type AppChangeType string
+
+const (
+	// AppStaked when the application was not present on the previous snapshot
+	AppStaked AppChangeType = "staked"
+	// AppUnstaked when the application is no longer present on the current snapshot
+	AppUnstaked AppChangeType = "unstaked"
+	// AppChainsChanged when the application is staked for a different set of chains
+	AppChainsChanged AppChangeType = "chains_changed"
+)
+
+// AppChange represents a change on a staked application between two snapshots
+type AppChange struct {
+	Type           AppChangeType `json:"type"`
+	PublicKey      string        `json:"publicKey"`
+	Chains         []string      `json:"chains"`
+	PreviousChains []string      `json:"previousChains,omitempty"`
+}
+
+// AppsSnapshot is the set of staked applications and their chains, keyed by public key
+type AppsSnapshot map[string][]string
+
+// NewAppsSnapshot returns the snapshot of the given staked applications
+func NewAppsSnapshot(apps []*provider.App) AppsSnapshot {
+	snapshot := make(AppsSnapshot, len(apps))
+	for _, app := range apps {
+		chains := slices.Clone(app.Chains)
+		slices.Sort(chains)
+		snapshot[app.PublicKey] = chains
+	}
+	return snapshot
+}
+
+// DiffAppsSnapshots returns all the changes needed to go from the previous snapshot
+// to the current one, sorted by application public key
+func DiffAppsSnapshots(previous, current AppsSnapshot) []*AppChange {
+	changes := []*AppChange{}
+
+	for publicKey, chains := range current {
+		previousChains, ok := previous[publicKey]
+		switch {
+		case !ok:
+			changes = append(changes, &AppChange{
+				Type:      AppStaked,
+				PublicKey: publicKey,
+				Chains:    chains,
+			})
+		case !slices.Equal(previousChains, chains):
+			changes = append(changes, &AppChange{
+				Type:           AppChainsChanged,
+				PublicKey:      publicKey,
+				Chains:         chains,
+				PreviousChains: previousChains,
+			})
+		}
+	}
+
+	for publicKey, previousChains := range previous {
+		if _, ok := current[publicKey]; !ok {
+			changes = append(changes, &AppChange{
+				Type:           AppUnstaked,
+				PublicKey:      publicKey,
+				PreviousChains: previousChains,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].PublicKey < changes[j].PublicKey
+	})
+
+	return changes
+}
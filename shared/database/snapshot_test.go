@@ -0,0 +1,37 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAppsSnapshots(t *testing.T) {
+	c := require.New(t)
+
+	previous := NewAppsSnapshot([]*provider.App{
+		{PublicKey: "unchanged", Chains: []string{"0021", "0001"}},
+		{PublicKey: "unstaked", Chains: []string{"0021"}},
+		{PublicKey: "rechained", Chains: []string{"0021"}},
+	})
+	current := NewAppsSnapshot([]*provider.App{
+		{PublicKey: "unchanged", Chains: []string{"0001", "0021"}},
+		{PublicKey: "rechained", Chains: []string{"0021", "0040"}},
+		{PublicKey: "new", Chains: []string{"0040"}},
+	})
+
+	changes := DiffAppsSnapshots(previous, current)
+	c.Len(changes, 3)
+
+	c.Equal(&AppChange{Type: AppStaked, PublicKey: "new", Chains: []string{"0040"}}, changes[0])
+	c.Equal(&AppChange{
+		Type:           AppChainsChanged,
+		PublicKey:      "rechained",
+		Chains:         []string{"0021", "0040"},
+		PreviousChains: []string{"0021"},
+	}, changes[1])
+	c.Equal(&AppChange{Type: AppUnstaked, PublicKey: "unstaked", PreviousChains: []string{"0021"}}, changes[2])
+
+	c.Empty(DiffAppsSnapshots(current, current))
+}
@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FreshnessMode is the rule used to decide whether a cached session is fresh enough
+type FreshnessMode string
+
+const (
+	// FreshnessAll requires all the checked stores to hold an up to date session
+	FreshnessAll FreshnessMode = "all"
+	// FreshnessMajority requires more than half of the checked stores to hold an up to date session
+	FreshnessMajority FreshnessMode = "majority"
+	// FreshnessAny requires at least N of the checked stores to hold an up to date session
+	FreshnessAny FreshnessMode = "any"
+)
+
+// FreshnessPolicy determines how many of the checked stores must hold an up
+// to date session for it to not be dispatched again
+type FreshnessPolicy struct {
+	Mode   FreshnessMode
+	Quorum int
+}
+
+// ParseFreshnessPolicy parses a policy given in the form "all", "majority" or "any-N"
+func ParseFreshnessPolicy(policy string) (FreshnessPolicy, error) {
+	switch mode := FreshnessMode(strings.ToLower(strings.TrimSpace(policy))); {
+	case mode == FreshnessAll, mode == "":
+		return FreshnessPolicy{Mode: FreshnessAll}, nil
+	case mode == FreshnessMajority:
+		return FreshnessPolicy{Mode: FreshnessMajority}, nil
+	case strings.HasPrefix(string(mode), string(FreshnessAny)+"-"):
+		quorum, err := strconv.Atoi(strings.TrimPrefix(string(mode), string(FreshnessAny)+"-"))
+		if err != nil || quorum <= 0 {
+			return FreshnessPolicy{}, fmt.Errorf("invalid quorum on freshness policy: %s", policy)
+		}
+		return FreshnessPolicy{Mode: FreshnessAny, Quorum: quorum}, nil
+	}
+
+	return FreshnessPolicy{}, fmt.Errorf("invalid freshness policy: %s", policy)
+}
+
+// RequiredFreshStores returns how many of the checked stores must be fresh to satisfy the policy
+func (p FreshnessPolicy) RequiredFreshStores(checkedStores int) int {
+	switch p.Mode {
+	case FreshnessMajority:
+		return checkedStores/2 + 1
+	case FreshnessAny:
+		if p.Quorum < checkedStores {
+			return p.Quorum
+		}
+	}
+
+	return checkedStores
+}
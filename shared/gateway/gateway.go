@@ -5,14 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
-	"sync/atomic"
+	"time"
 
-	"github.com/Pocket/global-services/shared/cache"
 	"github.com/Pocket/global-services/shared/environment"
 	httpClient "github.com/Pocket/global-services/shared/http"
 	"github.com/Pocket/global-services/shared/pocket"
 	"github.com/Pocket/global-services/shared/utils"
 	"github.com/pokt-foundation/pocket-go/provider"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
 )
 
 var gatewayURL = environment.GetString("GATEWAY_PRODUCTION_URL", "")
@@ -40,39 +42,77 @@ func GetSessionCacheKey(publicKey, chain, commitHash string) string {
 	return fmt.Sprintf("%s%s-%s-%s", commitHash, sessionKeyPrefix, publicKey, chain)
 }
 
-// ShouldDispatch checks N random cache clients and checks whether the session
-// is available and up to date with the current block, fails if any of the
-// clients fails the check and returns the session if found by any cache
-func ShouldDispatch(ctx context.Context, caches []*cache.Redis, blockHeight int, key string, maxClients int) (bool, *provider.Session) {
-	clientsToCheck := utils.Min(len(caches), maxClients)
-	clients := utils.Shuffle(caches)[0:clientsToCheck]
-	var globalCachedSession *pocket.Session
+// ShouldDispatchOptions are the options to check the freshness of a session on the stores
+type ShouldDispatchOptions struct {
+	BlockHeight int
+	Key         string
+	MaxClients  int
+	Policy      FreshnessPolicy
+	// RepairTTL is the ttl used to write the freshest session back to the stale
+	// stores once the policy is met, stale stores are left as is when zero
+	RepairTTL time.Duration
+}
 
-	var wg sync.WaitGroup
-	var cachedClients uint32
+// ShouldDispatch checks N random session stores and checks whether the session
+// is available and up to date with the current block, the session needs to be
+// dispatched if fewer stores than the ones required by the freshness policy are
+// up to date. Returns the freshest session found, if any.
+func ShouldDispatch(ctx context.Context, stores []SessionStore, options ShouldDispatchOptions) (bool, *provider.Session) {
+	clientsToCheck := utils.Min(len(stores), options.MaxClients)
+	clients := utils.Shuffle(stores)[0:clientsToCheck]
+	cachedSessions := make([]*pocket.Session, clientsToCheck)
 
-	for _, client := range clients {
+	var wg sync.WaitGroup
+	for index, client := range clients {
 		wg.Add(1)
-		go func(cl *cache.Redis) {
+		go func(idx int, store SessionStore) {
 			defer wg.Done()
 
-			rawSession, err := cl.Client.Get(ctx, cl.KeyPrefix+key).Result()
-			if err != nil || rawSession == "" {
-				return
-			}
-			var cachedSession pocket.Session
-			if err := json.Unmarshal([]byte(rawSession), &cachedSession); err != nil {
-				return
-			}
-			if cachedSession.BlockHeight < blockHeight {
+			cachedSession, err := store.Get(ctx, options.Key)
+			if err != nil || cachedSession.BlockHeight < options.BlockHeight {
 				return
 			}
 
-			atomic.AddUint32(&cachedClients, 1)
-			globalCachedSession = &cachedSession
-		}(client)
+			cachedSessions[idx] = cachedSession
+		}(index, client)
 	}
 	wg.Wait()
 
-	return cachedClients != uint32(clientsToCheck), globalCachedSession.ToProviderSession()
+	var freshestSession *pocket.Session
+	staleClients := []SessionStore{}
+	for idx, cachedSession := range cachedSessions {
+		if cachedSession == nil {
+			staleClients = append(staleClients, clients[idx])
+			continue
+		}
+		if freshestSession == nil || cachedSession.BlockHeight > freshestSession.BlockHeight {
+			freshestSession = cachedSession
+		}
+	}
+
+	freshClients := clientsToCheck - len(staleClients)
+	if freshClients < options.Policy.RequiredFreshStores(clientsToCheck) {
+		return true, freshestSession.ToProviderSession()
+	}
+
+	if options.RepairTTL > 0 && len(staleClients) > 0 {
+		repairStaleStores(ctx, staleClients, options.Key, freshestSession, options.RepairTTL)
+	}
+
+	return false, freshestSession.ToProviderSession()
+}
+
+// repairStaleStores writes the given session to the stores that didn't have it up to date
+func repairStaleStores(ctx context.Context, stores []SessionStore, key string, session *pocket.Session, ttl time.Duration) {
+	errs := utils.RunFnOnSliceMultipleFailures(stores, func(store SessionStore) error {
+		return store.Set(ctx, key, session, ttl)
+	})
+	for _, err := range errs {
+		if err != nil {
+			logger.Log.WithFields(log.Fields{
+				"key":   key,
+				"error": err.Error(),
+			}).Error("gateway: error repairing stale session: " + err.Error())
+		}
+	}
 }
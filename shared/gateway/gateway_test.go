@@ -0,0 +1,180 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Pocket/global-services/shared/cache"
+	"github.com/Pocket/global-services/shared/pocket"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSession(blockHeight int) *pocket.Session {
+	return &pocket.Session{
+		BlockHeight: blockHeight,
+		Key:         "session-key",
+		Header: &pocket.SessionHeader{
+			AppPublicKey:  "app-public-key",
+			Chain:         "0021",
+			SessionHeight: blockHeight,
+		},
+		Nodes: []*pocket.Node{{PublicKey: "node-public-key"}},
+	}
+}
+
+func TestShouldDispatch(t *testing.T) {
+	c := require.New(t)
+	ctx := context.Background()
+
+	key := GetSessionCacheKey("app-public-key", "0021", "")
+	stores := []SessionStore{NewMemorySessionStore(), NewMemorySessionStore()}
+	options := ShouldDispatchOptions{
+		BlockHeight: 10,
+		Key:         key,
+		MaxClients:  2,
+		Policy:      FreshnessPolicy{Mode: FreshnessAll},
+	}
+
+	shouldDispatch, session := ShouldDispatch(ctx, stores, options)
+	c.True(shouldDispatch)
+	c.Nil(session)
+
+	c.NoError(stores[0].Set(ctx, key, newTestSession(10), time.Minute))
+
+	shouldDispatch, session = ShouldDispatch(ctx, stores, options)
+	c.True(shouldDispatch)
+	c.Equal("session-key", session.Key)
+
+	c.NoError(stores[1].Set(ctx, key, newTestSession(10), time.Minute))
+
+	shouldDispatch, _ = ShouldDispatch(ctx, stores, options)
+	c.False(shouldDispatch)
+
+	options.BlockHeight = 11
+	shouldDispatch, _ = ShouldDispatch(ctx, stores, options)
+	c.True(shouldDispatch)
+}
+
+func TestShouldDispatchQuorumAndRepair(t *testing.T) {
+	c := require.New(t)
+	ctx := context.Background()
+
+	key := GetSessionCacheKey("app-public-key", "0021", "")
+	stores := []SessionStore{NewMemorySessionStore(), NewMemorySessionStore(), NewMemorySessionStore()}
+	c.NoError(stores[0].Set(ctx, key, newTestSession(12), time.Minute))
+	c.NoError(stores[1].Set(ctx, key, newTestSession(10), time.Minute))
+
+	options := ShouldDispatchOptions{
+		BlockHeight: 10,
+		Key:         key,
+		MaxClients:  3,
+		Policy:      FreshnessPolicy{Mode: FreshnessMajority},
+	}
+
+	shouldDispatch, session := ShouldDispatch(ctx, stores, options)
+	c.False(shouldDispatch)
+	c.Equal(12, session.Header.SessionHeight)
+
+	_, err := stores[2].Get(ctx, key)
+	c.ErrorIs(err, cache.ErrKeyDoesNotExist)
+
+	options.Policy = FreshnessPolicy{Mode: FreshnessAny, Quorum: 1}
+	options.RepairTTL = time.Minute
+
+	shouldDispatch, _ = ShouldDispatch(ctx, stores, options)
+	c.False(shouldDispatch)
+
+	repairedSession, err := stores[2].Get(ctx, key)
+	c.NoError(err)
+	c.Equal(12, repairedSession.BlockHeight)
+
+	options.BlockHeight = 13
+	shouldDispatch, session = ShouldDispatch(ctx, stores, options)
+	c.True(shouldDispatch)
+	c.Nil(session)
+}
+
+func TestParseFreshnessPolicy(t *testing.T) {
+	c := require.New(t)
+
+	policy, err := ParseFreshnessPolicy("majority")
+	c.NoError(err)
+	c.Equal(FreshnessPolicy{Mode: FreshnessMajority}, policy)
+	c.Equal(2, policy.RequiredFreshStores(3))
+
+	policy, err = ParseFreshnessPolicy("any-2")
+	c.NoError(err)
+	c.Equal(FreshnessPolicy{Mode: FreshnessAny, Quorum: 2}, policy)
+	c.Equal(2, policy.RequiredFreshStores(3))
+	c.Equal(1, policy.RequiredFreshStores(1))
+
+	policy, err = ParseFreshnessPolicy("")
+	c.NoError(err)
+	c.Equal(3, policy.RequiredFreshStores(3))
+
+	_, err = ParseFreshnessPolicy("any-zero")
+	c.Error(err)
+
+	_, err = ParseFreshnessPolicy("most")
+	c.Error(err)
+}
+
+func TestSessionBatchWriter(t *testing.T) {
+	c := require.New(t)
+	ctx := context.Background()
+
+	store := NewMemorySessionStore()
+	write := SessionBatchWriter([]SessionStore{store})
+
+	err := write(ctx, []*cache.Item{
+		{Key: "first", Value: newTestSession(5), TTL: time.Minute},
+		{Key: "second", Value: newTestSession(6), TTL: time.Hour},
+		{Key: "invalid", Value: "not a session", TTL: time.Hour},
+	})
+	c.EqualError(err, "items are not sessions: invalid")
+
+	var invalidItemsErr *InvalidSessionItemsError
+	c.ErrorAs(err, &invalidItemsErr)
+	c.Equal([]string{"invalid"}, invalidItemsErr.Keys)
+
+	sessions, err := store.MultiGet(ctx, []string{"first", "second", "invalid"})
+	c.NoError(err)
+	c.Equal(5, sessions[0].BlockHeight)
+	c.Equal(6, sessions[1].BlockHeight)
+	c.Nil(sessions[2])
+}
+
+func TestPromoteStagedSessions(t *testing.T) {
+	c := require.New(t)
+	ctx := context.Background()
+
+	stores := []SessionStore{NewMemorySessionStore(), NewMemorySessionStore()}
+	key := GetSessionCacheKey("app-public-key", "0021", "")
+	stagedKey := GetStagedSessionCacheKey("app-public-key", "0021", 5)
+	oldStagedKey := GetStagedSessionCacheKey("app-public-key", "0021", 1)
+
+	for _, store := range stores {
+		c.NoError(store.Set(ctx, key, newTestSession(1), 0))
+	}
+	c.NoError(stores[0].Set(ctx, stagedKey, newTestSession(5), 0))
+	c.NoError(stores[1].Set(ctx, oldStagedKey, newTestSession(1), 0))
+
+	promoted, err := PromoteStagedSessions(ctx, stores, map[string]string{stagedKey: key}, 5, 7, time.Minute)
+	c.NoError(err)
+	c.Equal(1, promoted)
+
+	session, err := stores[0].Get(ctx, key)
+	c.NoError(err)
+	c.Equal(5, session.Header.SessionHeight)
+	c.Equal(7, session.BlockHeight)
+
+	// The store without the staged session keeps the current one
+	session, err = stores[1].Get(ctx, key)
+	c.NoError(err)
+	c.Equal(1, session.Header.SessionHeight)
+
+	promoted, err = PromoteStagedSessions(ctx, stores, map[string]string{oldStagedKey: key}, 5, 7, time.Minute)
+	c.NoError(err)
+	c.Zero(promoted)
+}
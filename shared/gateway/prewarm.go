@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Pocket/global-services/shared/pocket"
+	"github.com/Pocket/global-services/shared/utils"
+)
+
+const stagedSessionKeyPrefix = "staged"
+
+// GetStagedSessionCacheKey returns the key a session is staged on before its
+// session height is reached, versioned by the session height
+func GetStagedSessionCacheKey(publicKey, chain string, sessionHeight int) string {
+	return fmt.Sprintf("%s-%d-%s", stagedSessionKeyPrefix, sessionHeight, GetSessionCacheKey(publicKey, chain, ""))
+}
+
+// PromoteStagedSessions copies the sessions staged for the given session height to their
+// session keys on all the stores. The sessions of each store are replaced in a single
+// transaction, so clients never read a partially promoted set of sessions.
+// The keys given are the session keys, the promoted sessions are marked as seen at the given
+// block height. Returns the amount of sessions promoted on all the stores.
+func PromoteStagedSessions(ctx context.Context, stores []SessionStore, keys map[string]string, sessionHeight, blockHeight int, ttl time.Duration) (int, error) {
+	stagedKeys := make([]string, 0, len(keys))
+	sessionKeys := make([]string, 0, len(keys))
+	for stagedKey, sessionKey := range keys {
+		stagedKeys = append(stagedKeys, stagedKey)
+		sessionKeys = append(sessionKeys, sessionKey)
+	}
+
+	var promoted int64
+	err := utils.RunFnOnSliceSingleFailure(stores, func(store SessionStore) error {
+		sessions, err := store.MultiGet(ctx, stagedKeys)
+		if err != nil {
+			return err
+		}
+
+		toPromote := make(map[string]*pocket.Session)
+		for i, session := range sessions {
+			// A staged session from another height must never replace the current one
+			if session == nil || session.Header == nil || session.Header.SessionHeight != sessionHeight {
+				continue
+			}
+			session.BlockHeight = blockHeight
+			toPromote[sessionKeys[i]] = session
+		}
+
+		if len(toPromote) == 0 {
+			return nil
+		}
+
+		if err := store.MultiSetAtomic(ctx, toPromote, ttl); err != nil {
+			return err
+		}
+		atomic.AddInt64(&promoted, int64(len(toPromote)))
+
+		return nil
+	})
+
+	return int(promoted), err
+}
@@ -0,0 +1,268 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pocket/global-services/shared/cache"
+	"github.com/Pocket/global-services/shared/pocket"
+	"github.com/Pocket/global-services/shared/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+// SessionStore is the interface for the storages of dispatched sessions,
+// sessions are keyed by the result of GetSessionCacheKey
+type SessionStore interface {
+	Get(ctx context.Context, key string) (*pocket.Session, error)
+	Set(ctx context.Context, key string, session *pocket.Session, ttl time.Duration) error
+	MultiGet(ctx context.Context, keys []string) ([]*pocket.Session, error)
+	MultiSet(ctx context.Context, sessions map[string]*pocket.Session, ttl time.Duration) error
+	// MultiSetAtomic saves all the sessions given at once, readers see either none or all of them
+	MultiSetAtomic(ctx context.Context, sessions map[string]*pocket.Session, ttl time.Duration) error
+}
+
+// RedisSessionStore is a session store backed by a redis client
+type RedisSessionStore struct {
+	Cache *cache.Redis
+}
+
+// NewRedisSessionStores returns a session store for every redis client given
+func NewRedisSessionStores(caches []*cache.Redis) []SessionStore {
+	stores := make([]SessionStore, 0, len(caches))
+	for _, cl := range caches {
+		stores = append(stores, &RedisSessionStore{Cache: cl})
+	}
+	return stores
+}
+
+// Get returns the session saved on the given key
+func (r *RedisSessionStore) Get(ctx context.Context, key string) (*pocket.Session, error) {
+	rawSession, err := r.Cache.Client.Get(ctx, r.Cache.KeyPrefix+key).Result()
+
+	var session pocket.Session
+	if err := cache.UnmarshallJSONResult(rawSession, err, &session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Set saves the session on the given key
+func (r *RedisSessionStore) Set(ctx context.Context, key string, session *pocket.Session, ttl time.Duration) error {
+	marshalledSession, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return r.Cache.Client.Set(ctx, r.Cache.KeyPrefix+key, marshalledSession, ttl).Err()
+}
+
+// MultiGet returns the sessions of all the keys given in the same order, keys
+// without a valid session have a nil value
+func (r *RedisSessionStore) MultiGet(ctx context.Context, keys []string) ([]*pocket.Session, error) {
+	prefixedKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixedKeys = append(prefixedKeys, r.Cache.KeyPrefix+key)
+	}
+
+	rawSessions, err := r.Cache.MGetPipe(ctx, prefixedKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*pocket.Session, len(keys))
+	for idx, rawSession := range rawSessions {
+		var session pocket.Session
+		if err := cache.UnmarshallJSONResult(rawSession, nil, &session); err != nil {
+			continue
+		}
+		sessions[idx] = &session
+	}
+
+	return sessions, nil
+}
+
+// MultiSet saves all the sessions given using a single pipeline
+func (r *RedisSessionStore) MultiSet(ctx context.Context, sessions map[string]*pocket.Session, ttl time.Duration) error {
+	items := make([]*cache.Item, 0, len(sessions))
+	for key, session := range sessions {
+		marshalledSession, err := json.Marshal(session)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, &cache.Item{
+			Key:   r.Cache.KeyPrefix + key,
+			Value: marshalledSession,
+			TTL:   ttl,
+		})
+	}
+
+	_, err := r.Cache.PipeOperation(ctx, items, func(pipe redis.Pipeliner, it *cache.Item) error {
+		return pipe.Set(ctx, it.Key, it.Value, it.TTL).Err()
+	})
+	return err
+}
+
+// MultiSetAtomic saves all the sessions given in a single MULTI/EXEC transaction. On redis
+// clusters transactions can't span hash slots, so the sessions of each slot are saved at once.
+func (r *RedisSessionStore) MultiSetAtomic(ctx context.Context, sessions map[string]*pocket.Session, ttl time.Duration) error {
+	marshalledSessions := make(map[string][]byte, len(sessions))
+	for key, session := range sessions {
+		marshalledSession, err := json.Marshal(session)
+		if err != nil {
+			return err
+		}
+		marshalledSessions[r.Cache.KeyPrefix+key] = marshalledSession
+	}
+
+	_, err := r.Cache.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, marshalledSession := range marshalledSessions {
+			pipe.Set(ctx, key, marshalledSession, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+type memorySession struct {
+	session   pocket.Session
+	expiresAt time.Time
+}
+
+// MemorySessionStore is an in-memory session store, meant for tests and local runs
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]memorySession
+}
+
+// NewMemorySessionStore returns an empty in-memory session store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]memorySession),
+	}
+}
+
+// Get returns the session saved on the given key
+func (m *MemorySessionStore) Get(ctx context.Context, key string) (*pocket.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, ok := m.sessions[key]
+	if !ok || (!stored.expiresAt.IsZero() && time.Now().After(stored.expiresAt)) {
+		return nil, cache.ErrKeyDoesNotExist
+	}
+
+	session := stored.session
+	return &session, nil
+}
+
+// Set saves the session on the given key, a ttl of 0 means the session never expires
+func (m *MemorySessionStore) Set(ctx context.Context, key string, session *pocket.Session, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[key] = newMemorySession(session, ttl)
+
+	return nil
+}
+
+func newMemorySession(session *pocket.Session, ttl time.Duration) memorySession {
+	stored := memorySession{session: *session}
+	if ttl > 0 {
+		stored.expiresAt = time.Now().Add(ttl)
+	}
+
+	return stored
+}
+
+// MultiGet returns the sessions of all the keys given in the same order, keys
+// without a valid session have a nil value
+func (m *MemorySessionStore) MultiGet(ctx context.Context, keys []string) ([]*pocket.Session, error) {
+	sessions := make([]*pocket.Session, len(keys))
+	for idx, key := range keys {
+		session, err := m.Get(ctx, key)
+		if err != nil {
+			continue
+		}
+		sessions[idx] = session
+	}
+
+	return sessions, nil
+}
+
+// MultiSet saves all the sessions given
+func (m *MemorySessionStore) MultiSet(ctx context.Context, sessions map[string]*pocket.Session, ttl time.Duration) error {
+	for key, session := range sessions {
+		if err := m.Set(ctx, key, session, ttl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MultiSetAtomic saves all the sessions given under the same lock
+func (m *MemorySessionStore) MultiSetAtomic(ctx context.Context, sessions map[string]*pocket.Session, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, session := range sessions {
+		m.sessions[key] = newMemorySession(session, ttl)
+	}
+
+	return nil
+}
+
+// InvalidSessionItemsError when some items of a batch are not sessions, the rest
+// of the items are still written
+type InvalidSessionItemsError struct {
+	Keys []string
+}
+
+func (e *InvalidSessionItemsError) Error() string {
+	return "items are not sessions: " + strings.Join(e.Keys, ", ")
+}
+
+// SessionBatchWriter returns a cache batch writer which saves the sessions of the
+// items on all the stores given, the items' values must be of type *pocket.Session.
+// Items that are not sessions are not written and make the writer return an
+// *InvalidSessionItemsError.
+func SessionBatchWriter(stores []SessionStore) func(ctx context.Context, items []*cache.Item) error {
+	return func(ctx context.Context, items []*cache.Item) error {
+		sessionsByTTL := make(map[time.Duration]map[string]*pocket.Session)
+		invalidKeys := []string{}
+		for _, item := range items {
+			session, ok := item.Value.(*pocket.Session)
+			if !ok {
+				invalidKeys = append(invalidKeys, item.Key)
+				continue
+			}
+			if _, ok := sessionsByTTL[item.TTL]; !ok {
+				sessionsByTTL[item.TTL] = make(map[string]*pocket.Session)
+			}
+			sessionsByTTL[item.TTL][item.Key] = session
+		}
+
+		err := utils.RunFnOnSliceSingleFailure(stores, func(store SessionStore) error {
+			for ttl, sessions := range sessionsByTTL {
+				if err := store.MultiSet(ctx, sessions, ttl); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(invalidKeys) > 0 {
+			return &InvalidSessionItemsError{Keys: invalidKeys}
+		}
+
+		return nil
+	}
+}
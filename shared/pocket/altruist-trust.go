@@ -0,0 +1,142 @@
+package pocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	"github.com/Pocket/global-services/shared/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// altruistCredentialsRegex matches the scheme and credentials of an altruist url
+var altruistCredentialsRegex = regexp.MustCompile(`[\w]*://[^/]*@`)
+
+// AltruistTrustOptions sets when the altruist is no longer trusted as the reference block height,
+// zero values fall back to the defaults of the sync checker
+type AltruistTrustOptions struct {
+	// Threshold is the ratio of valid nodes ahead of the altruist that makes it untrustworthy
+	Threshold float32 `json:"threshold"`
+	// MinValidNodes is the minimum amount of valid nodes needed to distrust the altruist
+	MinValidNodes int `json:"minValidNodes"`
+}
+
+// AltruistTrustDecision is the verdict on the altruist of a sync check along with its inputs
+type AltruistTrustDecision struct {
+	Trusted              bool    `json:"trusted"`
+	Reason               string  `json:"reason"`
+	AltruistBlockHeight  int64   `json:"altruistBlockHeight"`
+	HighestBlockHeight   int64   `json:"highestBlockHeight"`
+	NodesAheadOfAltruist int     `json:"nodesAheadOfAltruist"`
+	ValidNodes           int     `json:"validNodes"`
+	Ratio                float32 `json:"ratio"`
+	Threshold            float32 `json:"threshold"`
+	MinValidNodes        int     `json:"minValidNodes"`
+}
+
+// altruistTrustOptions returns the trust options of the chain with the defaults applied
+func (sc *SyncChecker) altruistTrustOptions(options *SyncCheckOptions) AltruistTrustOptions {
+	trust := AltruistTrustOptions{
+		Threshold:     sc.AltruistTrustThreshold,
+		MinValidNodes: sc.AltruistMinValidNodes,
+	}
+
+	if options.AltruistTrust != nil {
+		if options.AltruistTrust.Threshold > 0 {
+			trust.Threshold = options.AltruistTrust.Threshold
+		}
+		if options.AltruistTrust.MinValidNodes > 0 {
+			trust.MinValidNodes = options.AltruistTrust.MinValidNodes
+		}
+	}
+
+	return trust
+}
+
+// evaluateAltruistTrust decides whether the altruist block height can be trusted, the altruist
+// is distrusted when too many valid nodes are ahead of it, as long as there are enough valid nodes
+func evaluateAltruistTrust(altruistBlockHeight, highestBlockHeight int64, nodesAheadOfAltruist, validNodes int, options AltruistTrustOptions) AltruistTrustDecision {
+	decision := AltruistTrustDecision{
+		AltruistBlockHeight:  altruistBlockHeight,
+		HighestBlockHeight:   highestBlockHeight,
+		NodesAheadOfAltruist: nodesAheadOfAltruist,
+		ValidNodes:           validNodes,
+		Threshold:            options.Threshold,
+		MinValidNodes:        options.MinValidNodes,
+	}
+
+	if validNodes > 0 {
+		decision.Ratio = float32(nodesAheadOfAltruist) / float32(validNodes)
+	}
+
+	switch {
+	case altruistBlockHeight <= 0:
+		decision.Reason = "no altruist block height"
+	case highestBlockHeight <= 0:
+		decision.Trusted = true
+		decision.Reason = "no highest block height of the nodes to distrust the altruist"
+	case validNodes < options.MinValidNodes:
+		decision.Trusted = true
+		decision.Reason = "not enough valid nodes to distrust the altruist"
+	case decision.Ratio >= options.Threshold:
+		decision.Reason = "too many nodes ahead of the altruist"
+	default:
+		decision.Trusted = true
+		decision.Reason = "nodes ahead of the altruist under the threshold"
+	}
+
+	return decision
+}
+
+// recordAltruistTrust logs the trust decision and writes it to the metrics db for auditing,
+// trusted and distrusted decisions are told apart by their code
+func (sc *SyncChecker) recordAltruistTrust(ctx context.Context, decision AltruistTrustDecision, options *SyncCheckOptions) {
+	logger.Log.WithFields(log.Fields{
+		"sessionKey":           options.Session.Key,
+		"blockchainID":         options.Blockchain,
+		"requestID":            sc.RequestID,
+		"serviceNode":          "ALTRUIST",
+		"trusted":              decision.Trusted,
+		"altruistBlockHeight":  decision.AltruistBlockHeight,
+		"highestBlockHeight":   decision.HighestBlockHeight,
+		"nodesAheadOfAltruist": decision.NodesAheadOfAltruist,
+		"validNodes":           decision.ValidNodes,
+		"ratio":                decision.Ratio,
+		"threshold":            decision.Threshold,
+		"minValidNodes":        decision.MinValidNodes,
+	}).Info(fmt.Sprintf("sync check: altruist trust: %t: %s", decision.Trusted, decision.Reason))
+
+	if sc.MetricsRecorder == nil {
+		return
+	}
+
+	message, err := json.Marshal(decision)
+	if err != nil {
+		return
+	}
+
+	code := "distrusted"
+	if decision.Trusted {
+		code = "trusted"
+	}
+
+	sc.MetricsRecorder.WriteErrorMetric(ctx, &metrics.Metric{
+		Timestamp:            time.Now(),
+		ApplicationPublicKey: options.Session.Header.AppPublicKey,
+		Blockchain:           options.Blockchain,
+		NodePublicKey:        "ALTRUIST",
+		Bytes:                len(message),
+		Method:               "altruisttrust",
+		Message:              string(message),
+		Code:                 code,
+		RequestID:            sc.RequestID,
+	})
+}
+
+// redactAltruistURL removes the scheme and credentials of an altruist url so it can be logged
+func redactAltruistURL(altruistURL string) string {
+	return altruistCredentialsRegex.ReplaceAllString(altruistURL, "")
+}
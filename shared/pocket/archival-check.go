@@ -0,0 +1,329 @@
+package pocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pocket/global-services/shared/environment"
+	logger "github.com/Pocket/global-services/shared/logger"
+	"github.com/Pocket/global-services/shared/metrics"
+	"github.com/Pocket/global-services/shared/utils"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-foundation/pocket-go/relayer"
+	"github.com/pokt-foundation/portal-db/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	archivalCheckKeyPrefix = environment.GetString("ARCHIVAL_CHECK_KEY_PREFIX", "archival-check-")
+	// archivalCheckChains is a JSON object of the chains that require archival nodes and the
+	// historical query to check them, i.e {"0022": {"body": "...", "resultKey": "result"}}
+	archivalCheckChains = environment.GetString("ARCHIVAL_CHECK_CHAINS", "")
+)
+
+// ArchivalCheckName is the name of the archival check
+const ArchivalCheckName = "archivalcheck"
+
+// minArchivalMajority is the minimum amount of nodes that must agree on an answer when
+// the altruist is not available to compare against
+const minArchivalMajority = 2
+
+func init() {
+	RegisterCheck(ArchivalCheckName, newArchivalCheck)
+}
+
+// ArchivalQuery is a historical query only archival nodes are able to answer
+type ArchivalQuery struct {
+	Body      string `json:"body"`
+	Path      string `json:"path"`
+	ResultKey string `json:"resultKey"`
+}
+
+// ArchivalChecker is the struct to perform archival checks on app sessions
+type ArchivalChecker struct {
+	Relayer         *relayer.Relayer
+	MetricsRecorder *metrics.Recorder
+	RequestID       string
+}
+
+// ArchivalCheckOptions is the struct of the data needed to perform an archival check
+type ArchivalCheckOptions struct {
+	Session     provider.Session
+	PocketAAT   provider.PocketAAT
+	Query       ArchivalQuery
+	AltruistURL string
+	Blockchain  string
+}
+
+type nodeArchivalLog struct {
+	Node   *provider.Node
+	Result string
+}
+
+// Check performs an archival check of all the nodes of a given session, only the nodes
+// whose answer to the historical query matches the altruist's pass it
+func (ac *ArchivalChecker) Check(ctx context.Context, options ArchivalCheckOptions) []string {
+	checkedNodes := []string{}
+	nodeLogs := ac.getNodeArchivalLogs(ctx, &options)
+
+	expectedResult, err := getAltruistArchivalResult(options.AltruistURL, options.Query)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"sessionKey":   options.Session.Key,
+			"blockchainID": options.Blockchain,
+			"requestID":    ac.RequestID,
+			"serviceNode":  "ALTRUIST",
+			"error":        err.Error(),
+		}).Error("archival check: altruist failure: " + err.Error())
+
+		// Without the altruist the answer most nodes agree on is the expected one
+		expectedResult = getMajorityArchivalResult(nodeLogs)
+	}
+
+	for _, node := range nodeLogs {
+		if node.Result == "" {
+			continue
+		}
+
+		if expectedResult == "" || node.Result != expectedResult {
+			logger.Log.WithFields(log.Fields{
+				"sessionKey":            options.Session.Key,
+				"blockchainID":          options.Blockchain,
+				"requestID":             ac.RequestID,
+				"serviceURL":            node.Node.ServiceURL,
+				"serviceDomain":         utils.GetDomainFromURL(node.Node.ServiceURL),
+				"serviceNode":           node.Node.PublicKey,
+				"appplicationPublicKey": options.Session.Header.AppPublicKey,
+			}).Warn(fmt.Sprintf("ARCHIVAL CHECK FAILURE: %s result: %s", node.Node.PublicKey, node.Result))
+			continue
+		}
+
+		logger.Log.WithFields(log.Fields{
+			"sessionKey":            options.Session.Key,
+			"blockchainID":          options.Blockchain,
+			"requestID":             ac.RequestID,
+			"serviceURL":            node.Node.ServiceURL,
+			"serviceDomain":         utils.GetDomainFromURL(node.Node.ServiceURL),
+			"serviceNode":           node.Node.PublicKey,
+			"appplicationPublicKey": options.Session.Header.AppPublicKey,
+		}).Info(fmt.Sprintf("ARCHIVAL CHECK SUCCESS: %s", node.Node.PublicKey))
+
+		checkedNodes = append(checkedNodes, node.Node.PublicKey)
+	}
+
+	logger.Log.WithFields(log.Fields{
+		"sessionKey":            options.Session.Key,
+		"blockchainID":          options.Blockchain,
+		"requestID":             ac.RequestID,
+		"appplicationPublicKey": options.Session.Header.AppPublicKey,
+	}).Info(fmt.Sprintf("ARCHIVAL CHECK COMPLETE: %d archival nodes", len(checkedNodes)))
+
+	return checkedNodes
+}
+
+func (ac *ArchivalChecker) getNodeArchivalLogs(ctx context.Context, options *ArchivalCheckOptions) []*nodeArchivalLog {
+	nodeLogsChan := make(chan *nodeArchivalLog, len(options.Session.Nodes))
+	nodeLogs := []*nodeArchivalLog{}
+
+	var wg sync.WaitGroup
+	for _, node := range options.Session.Nodes {
+		wg.Add(1)
+		go func(n *provider.Node) {
+			defer wg.Done()
+			ac.getNodeArchivalLog(ctx, n, nodeLogsChan, options)
+		}(node)
+	}
+	wg.Wait()
+
+	close(nodeLogsChan)
+
+	for log := range nodeLogsChan {
+		nodeLogs = append(nodeLogs, log)
+	}
+
+	return nodeLogs
+}
+
+func (ac *ArchivalChecker) getNodeArchivalLog(ctx context.Context, node *provider.Node, nodeLogs chan<- *nodeArchivalLog, options *ArchivalCheckOptions) {
+	start := time.Now()
+
+	result, err := func() (string, error) {
+		relay, err := ac.Relayer.Relay(&relayer.Input{
+			Blockchain: options.Blockchain,
+			Data:       strings.Replace(options.Query.Body, `\`, "", -1),
+			Method:     http.MethodPost,
+			PocketAAT:  &options.PocketAAT,
+			Session:    &options.Session,
+			Node:       node,
+			Path:       options.Query.Path,
+		}, nil)
+		if err != nil {
+			return "", errors.New("error relaying: " + err.Error())
+		}
+
+		return getArchivalResult(relay.RelayOutput.Response, options.Query.ResultKey)
+	}()
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"sessionKey":    options.Session.Key,
+			"blockchainID":  options.Blockchain,
+			"requestID":     ac.RequestID,
+			"serviceURL":    node.ServiceURL,
+			"serviceDomain": utils.GetDomainFromURL(node.ServiceURL),
+			"serviceNode":   node.PublicKey,
+			"error":         err.Error(),
+		}).Error("archival check: error obtaining historical result: " + err.Error())
+
+		ac.MetricsRecorder.WriteErrorMetric(ctx, &metrics.Metric{
+			Timestamp:            time.Now(),
+			ApplicationPublicKey: options.Session.Header.AppPublicKey,
+			Blockchain:           options.Blockchain,
+			NodePublicKey:        node.PublicKey,
+			ElapsedTime:          time.Since(start).Seconds(),
+			Bytes:                len(err.Error()),
+			Method:               "archivalcheck",
+			Message:              err.Error(),
+			RequestID:            ac.RequestID,
+		})
+	}
+
+	nodeLogs <- &nodeArchivalLog{
+		Node:   node,
+		Result: result,
+	}
+}
+
+// getArchivalResult returns the value of the result key as its JSON representation,
+// so results of any type can be compared
+func getArchivalResult(payload, resultKey string) (string, error) {
+	document, err := utils.DecodeJSONPayload(payload, resultKey)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := utils.LookupJSONPath(document, resultKey)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		return "", fmt.Errorf("empty result on key %s", resultKey)
+	}
+
+	result, err := json.Marshal(value)
+	if err != nil {
+		return "", errors.New("error encoding result: " + err.Error())
+	}
+
+	return string(result), nil
+}
+
+// getMajorityArchivalResult returns the result most nodes agree on, empty if less than
+// the minimum amount of nodes agree on any result or there's a tie
+func getMajorityArchivalResult(nodeLogs []*nodeArchivalLog) string {
+	votes := make(map[string]int)
+	for _, node := range nodeLogs {
+		if node.Result != "" {
+			votes[node.Result]++
+		}
+	}
+
+	majorityResult, majorityVotes, isTied := "", 0, false
+	for result, count := range votes {
+		switch {
+		case count > majorityVotes:
+			majorityResult, majorityVotes, isTied = result, count, false
+		case count == majorityVotes:
+			isTied = true
+		}
+	}
+
+	if isTied || majorityVotes < minArchivalMajority {
+		return ""
+	}
+
+	return majorityResult
+}
+
+func getAltruistArchivalResult(altruistURL string, query ArchivalQuery) (string, error) {
+	if altruistURL == "" {
+		return "", errors.New("no altruist url")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, altruistURL+query.Path,
+		bytes.NewBuffer([]byte(strings.Replace(query.Body, `\`, "", -1))))
+	if err != nil {
+		return "", errors.New("error making altruist request: " + err.Error())
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := httpClient.Do(req)
+	defer utils.CloseOrLog(res)
+	if err != nil {
+		return "", errors.New("error performing altruist request: " + err.Error())
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.New("error reading altruist response: " + err.Error())
+	}
+
+	return getArchivalResult(string(body), query.ResultKey)
+}
+
+// archivalCheck is the Check implementation of the archival checker
+type archivalCheck struct {
+	checker *ArchivalChecker
+	deps    *CheckDependencies
+	queries map[string]*ArchivalQuery
+}
+
+func newArchivalCheck(deps *CheckDependencies) (Check, error) {
+	queries := map[string]*ArchivalQuery{}
+	if archivalCheckChains != "" {
+		if err := json.Unmarshal([]byte(archivalCheckChains), &queries); err != nil {
+			return nil, errors.New("error parsing archival check chains: " + err.Error())
+		}
+	}
+
+	return &archivalCheck{
+		checker: &ArchivalChecker{
+			Relayer:         deps.Relayer,
+			MetricsRecorder: deps.MetricsRecorder,
+			RequestID:       deps.RequestID,
+		},
+		deps:    deps,
+		queries: queries,
+	}, nil
+}
+
+func (a *archivalCheck) Name() string {
+	return ArchivalCheckName
+}
+
+func (a *archivalCheck) IsApplicable(blockchain *types.Blockchain) bool {
+	query, ok := a.queries[blockchain.ID]
+	return ok && query != nil && query.Body != ""
+}
+
+func (a *archivalCheck) Run(ctx context.Context, options *CheckOptions) []string {
+	return a.checker.Check(ctx, ArchivalCheckOptions{
+		Session:     options.Session,
+		PocketAAT:   options.PocketAAT,
+		Query:       *a.queries[options.Blockchain.ID],
+		AltruistURL: options.Blockchain.Altruist,
+		Blockchain:  options.Blockchain.ID,
+	})
+}
+
+func (a *archivalCheck) CachePolicy(sessionKey string) CachePolicy {
+	return newCachePolicy(a.deps, archivalCheckKeyPrefix, sessionKey)
+}
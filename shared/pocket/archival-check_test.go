@@ -0,0 +1,39 @@
+package pocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetArchivalResult(t *testing.T) {
+	c := require.New(t)
+
+	result, err := getArchivalResult(`{"result":"0x1bc16d674ec80000"}`, "result")
+	c.NoError(err)
+	c.Equal(`"0x1bc16d674ec80000"`, result)
+
+	result, err = getArchivalResult(`{"result":{"balance":12}}`, "result")
+	c.NoError(err)
+	c.Equal(`{"balance":12}`, result)
+
+	_, err = getArchivalResult(`{"result":null}`, "result")
+	c.Error(err)
+
+	_, err = getArchivalResult(`{"error":"missing trie node"}`, "result")
+	c.Error(err)
+}
+
+func TestGetMajorityArchivalResult(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal(`"0x1"`, getMajorityArchivalResult([]*nodeArchivalLog{
+		{Result: `"0x1"`}, {Result: `"0x1"`}, {Result: `"0x2"`}, {Result: ""},
+	}))
+
+	c.Empty(getMajorityArchivalResult([]*nodeArchivalLog{
+		{Result: `"0x1"`}, {Result: `"0x2"`},
+	}))
+
+	c.Empty(getMajorityArchivalResult([]*nodeArchivalLog{{Result: `"0x1"`}}))
+}
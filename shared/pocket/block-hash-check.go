@@ -0,0 +1,264 @@
+package pocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	"github.com/Pocket/global-services/shared/metrics"
+	"github.com/Pocket/global-services/shared/utils"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-foundation/pocket-go/relayer"
+	log "github.com/sirupsen/logrus"
+)
+
+// blockHeightPlaceholder is replaced on the block hash check body with the height to check
+const blockHeightPlaceholder = "{height}"
+
+// Defaults of the block hash check, made for EVM chains
+const (
+	defaultBlockHashBody      = `{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["{height}",false],"id":1}`
+	defaultBlockHashResultKey = "result.hash"
+)
+
+// BlockHashCheckOptions are the options to fetch the hash of a block of a chain, the body
+// must contain the {height} placeholder, which is replaced with the height to check
+type BlockHashCheckOptions struct {
+	Body      string `json:"body"`
+	Path      string `json:"path"`
+	ResultKey string `json:"resultKey"`
+	// DecimalHeight formats the height as a decimal number instead of hex
+	DecimalHeight bool `json:"decimalHeight"`
+}
+
+// withDefaults fills the empty fields of the options with the EVM defaults
+func (o BlockHashCheckOptions) withDefaults() BlockHashCheckOptions {
+	if o.Body == "" {
+		o.Body = defaultBlockHashBody
+	}
+	if o.ResultKey == "" {
+		o.ResultKey = defaultBlockHashResultKey
+	}
+	return o
+}
+
+func (o BlockHashCheckOptions) body(blockHeight int64) string {
+	height := "0x" + strconv.FormatInt(blockHeight, 16)
+	if o.DecimalHeight {
+		height = strconv.FormatInt(blockHeight, 10)
+	}
+
+	return strings.ReplaceAll(o.Body, blockHeightPlaceholder, height)
+}
+
+type nodeBlockHash struct {
+	Node *provider.Node
+	Hash string
+}
+
+// checkBlockHashes fetches the hash of the same block from all the nodes given and drops the
+// ones disagreeing with the majority, the altruist is used to break ties. Nodes whose hash
+// couldn't be fetched are kept. Returns the nodes unchanged when there's no majority.
+func (sc *SyncChecker) checkBlockHashes(ctx context.Context, nodeLogs []*nodeSyncLog, options *SyncCheckOptions) []*nodeSyncLog {
+	if len(nodeLogs) == 0 {
+		return nodeLogs
+	}
+
+	hashOptions := options.BlockHashCheck.withDefaults()
+
+	// All the nodes are expected to have the block at the lowest height that passed the sync check
+	commonHeight := nodeLogs[0].BlockHeight
+	for _, node := range nodeLogs {
+		if node.BlockHeight < commonHeight {
+			commonHeight = node.BlockHeight
+		}
+	}
+
+	hashes := sc.getNodeBlockHashes(ctx, nodeLogs, hashOptions, commonHeight, options)
+
+	altruistHash, err := getAltruistBlockHash(options.AltruistURL, hashOptions, commonHeight)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"sessionKey":   options.Session.Key,
+			"blockchainID": options.Blockchain,
+			"requestID":    sc.RequestID,
+			"serviceNode":  "ALTRUIST",
+			"error":        err.Error(),
+		}).Error("block hash check: altruist failure: " + err.Error())
+	}
+
+	majorityHash, ok := getMajorityBlockHash(hashes, altruistHash)
+	if !ok {
+		logger.Log.WithFields(log.Fields{
+			"sessionKey":   options.Session.Key,
+			"blockchainID": options.Blockchain,
+			"requestID":    sc.RequestID,
+			"blockHeight":  commonHeight,
+		}).Warn("block hash check: no majority of nodes agree on the block hash")
+		return nodeLogs
+	}
+
+	nodeHashes := make(map[string]string, len(hashes))
+	for _, hash := range hashes {
+		nodeHashes[hash.Node.PublicKey] = hash.Hash
+	}
+
+	validNodes := []*nodeSyncLog{}
+	for _, node := range nodeLogs {
+		hash, ok := nodeHashes[node.Node.PublicKey]
+		if !ok {
+			// Failing to return the hash is not evidence of being on another fork
+			logger.Log.WithFields(log.Fields{
+				"sessionKey":            options.Session.Key,
+				"blockchainID":          options.Blockchain,
+				"requestID":             sc.RequestID,
+				"serviceURL":            node.Node.ServiceURL,
+				"serviceDomain":         utils.GetDomainFromURL(node.Node.ServiceURL),
+				"serviceNode":           node.Node.PublicKey,
+				"appplicationPublicKey": options.Session.Header.AppPublicKey,
+			}).Warn(fmt.Sprintf("SYNC CHECK BLOCK HASH UNAVAILABLE: %s height: %d", node.Node.PublicKey, commonHeight))
+			validNodes = append(validNodes, node)
+			continue
+		}
+
+		if hash != majorityHash {
+			logger.Log.WithFields(log.Fields{
+				"sessionKey":            options.Session.Key,
+				"blockchainID":          options.Blockchain,
+				"requestID":             sc.RequestID,
+				"serviceURL":            node.Node.ServiceURL,
+				"serviceDomain":         utils.GetDomainFromURL(node.Node.ServiceURL),
+				"serviceNode":           node.Node.PublicKey,
+				"appplicationPublicKey": options.Session.Header.AppPublicKey,
+			}).Warn(fmt.Sprintf("SYNC CHECK WRONG FORK: %s height: %d hash: %s expected: %s",
+				node.Node.PublicKey, commonHeight, hash, majorityHash))
+			continue
+		}
+
+		validNodes = append(validNodes, node)
+	}
+
+	return validNodes
+}
+
+func (sc *SyncChecker) getNodeBlockHashes(ctx context.Context, nodeLogs []*nodeSyncLog, hashOptions BlockHashCheckOptions, blockHeight int64, options *SyncCheckOptions) []*nodeBlockHash {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	hashes := []*nodeBlockHash{}
+
+	for _, node := range nodeLogs {
+		wg.Add(1)
+		go func(n *provider.Node) {
+			defer wg.Done()
+
+			start := time.Now()
+			hash, _, err := utils.GetStringFromRelay(*sc.Relayer, relayer.Input{
+				Blockchain: options.Blockchain,
+				Data:       hashOptions.body(blockHeight),
+				Method:     http.MethodPost,
+				PocketAAT:  &options.PocketAAT,
+				Session:    &options.Session,
+				Node:       n,
+				Path:       hashOptions.Path,
+			}, hashOptions.ResultKey)
+			if err == nil && hash == "" {
+				err = errors.New("empty block hash")
+			}
+			if err != nil {
+				logger.Log.WithFields(log.Fields{
+					"sessionKey":    options.Session.Key,
+					"blockchainID":  options.Blockchain,
+					"requestID":     sc.RequestID,
+					"serviceURL":    n.ServiceURL,
+					"serviceDomain": utils.GetDomainFromURL(n.ServiceURL),
+					"error":         err.Error(),
+				}).Error("block hash check: error obtaining block hash: " + err.Error())
+
+				sc.MetricsRecorder.WriteErrorMetric(ctx, &metrics.Metric{
+					Timestamp:            time.Now(),
+					ApplicationPublicKey: options.Session.Header.AppPublicKey,
+					Blockchain:           options.Blockchain,
+					NodePublicKey:        n.PublicKey,
+					ElapsedTime:          time.Since(start).Seconds(),
+					Bytes:                len(err.Error()),
+					Method:               "blockhashcheck",
+					Message:              err.Error(),
+					RequestID:            sc.RequestID,
+				})
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			hashes = append(hashes, &nodeBlockHash{
+				Node: n,
+				Hash: strings.ToLower(hash),
+			})
+		}(node.Node)
+	}
+	wg.Wait()
+
+	return hashes
+}
+
+// getMajorityBlockHash returns the hash more than half of the nodes agree on. Without a majority
+// the altruist hash counts as one more vote, so it can only break ties that leave it with a
+// majority. Returns false if there's no majority.
+func getMajorityBlockHash(hashes []*nodeBlockHash, altruistHash string) (string, bool) {
+	votes := make(map[string]int)
+	for _, hash := range hashes {
+		votes[hash.Hash]++
+	}
+
+	for hash, count := range votes {
+		if count*2 > len(hashes) {
+			return hash, true
+		}
+	}
+
+	if altruistHash != "" && votes[altruistHash] > 0 && (votes[altruistHash]+1)*2 > len(hashes)+1 {
+		return altruistHash, true
+	}
+
+	return "", false
+}
+
+func getAltruistBlockHash(altruistURL string, options BlockHashCheckOptions, blockHeight int64) (string, error) {
+	if altruistURL == "" {
+		return "", errors.New("no altruist url")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, altruistURL+options.Path,
+		bytes.NewBuffer([]byte(options.body(blockHeight))))
+	if err != nil {
+		return "", errors.New("error making altruist request: " + err.Error())
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := httpClient.Do(req)
+	defer utils.CloseOrLog(res)
+	if err != nil {
+		return "", errors.New("error performing altruist request: " + err.Error())
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.New("error reading altruist response: " + err.Error())
+	}
+
+	hash, err := utils.ParseStringJSONString(string(body), options.ResultKey)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(hash), nil
+}
@@ -0,0 +1,49 @@
+package pocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMajorityBlockHash(t *testing.T) {
+	c := require.New(t)
+
+	hashes := []*nodeBlockHash{{Hash: "0xa"}, {Hash: "0xa"}, {Hash: "0xb"}}
+	hash, ok := getMajorityBlockHash(hashes, "")
+	c.True(ok)
+	c.Equal("0xa", hash)
+
+	// A plurality is not a majority
+	hashes = []*nodeBlockHash{{Hash: "0xa"}, {Hash: "0xa"}, {Hash: "0xb"}, {Hash: "0xc"}, {Hash: "0xd"}}
+	_, ok = getMajorityBlockHash(hashes, "")
+	c.False(ok)
+
+	// Nor it is with the altruist vote
+	hashes = []*nodeBlockHash{{Hash: "0xa"}, {Hash: "0xa"}, {Hash: "0xb"}, {Hash: "0xb"}, {Hash: "0xc"}}
+	_, ok = getMajorityBlockHash(hashes, "0xa")
+	c.False(ok)
+
+	// Ties are broken by the altruist
+	hashes = []*nodeBlockHash{{Hash: "0xa"}, {Hash: "0xb"}}
+	hash, ok = getMajorityBlockHash(hashes, "0xb")
+	c.True(ok)
+	c.Equal("0xb", hash)
+
+	_, ok = getMajorityBlockHash(hashes, "0xc")
+	c.False(ok)
+
+	_, ok = getMajorityBlockHash(nil, "0xa")
+	c.False(ok)
+}
+
+func TestBlockHashCheckOptions_Body(t *testing.T) {
+	c := require.New(t)
+
+	options := BlockHashCheckOptions{}.withDefaults()
+	c.Equal(`{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["0x10",false],"id":1}`, options.body(16))
+
+	options = BlockHashCheckOptions{Body: `{"height":{height}}`, DecimalHeight: true}.withDefaults()
+	c.Equal(`{"height":16}`, options.body(16))
+	c.Equal(defaultBlockHashResultKey, options.ResultKey)
+}
@@ -2,26 +2,75 @@ package pocket
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Pocket/global-services/shared/environment"
 	logger "github.com/Pocket/global-services/shared/logger"
 	"github.com/Pocket/global-services/shared/metrics"
 	"github.com/Pocket/global-services/shared/utils"
 	"github.com/pokt-foundation/pocket-go/provider"
 	"github.com/pokt-foundation/pocket-go/relayer"
+	"github.com/pokt-foundation/portal-db/types"
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	chainCheckKeyPrefix = environment.GetString("CHAIN_CHECK_KEY_PREFIX", "chain-check-")
+	// chainCheckChains is a JSON object of the chains whose chain check differs from the
+	// JSON-RPC default or the blockchain's ChainIDCheck, i.e {"0003": {"method": "GET",
+	// "path": "/node_info", "resultKey": "node_info.network", "chainID": "osmosis-", "match": "prefix"}}
+	chainCheckChains = environment.GetString("CHAIN_CHECK_CHAINS", "")
+)
+
+// ChainCheckName is the name of the chain check
+const ChainCheckName = "chaincheck"
+
+// Ways to compare the chain id reported by the nodes against the expected one
+const (
+	// ChainIDMatchExact requires the same chain id, integer chain ids are compared by value
+	// so hex and decimal ids are equal
+	ChainIDMatchExact = "exact"
+	// ChainIDMatchPrefix requires the chain id to start with the expected one
+	ChainIDMatchPrefix = "prefix"
+	// ChainIDMatchRegex requires the whole chain id to match the expected regular expression
+	ChainIDMatchRegex = "regex"
+)
+
+const defaultChainIDResultKey = "result"
+
+// ChainIDCheckOptions extend the chain check of a blockchain, empty fields use the
+// blockchain's ChainIDCheck, Path and ChainID
+type ChainIDCheckOptions struct {
+	Method    string `json:"method"`
+	Body      string `json:"body"`
+	Path      string `json:"path"`
+	ResultKey string `json:"resultKey"`
+	ChainID   string `json:"chainID"`
+	Match     string `json:"match"`
+}
+
+func init() {
+	RegisterCheck(ChainCheckName, newChainCheck)
+}
+
 // ChainChecker is the struct to perform chain checks on app sessions
 type ChainChecker struct {
 	Relayer         *relayer.Relayer
 	MetricsRecorder *metrics.Recorder
 	RequestID       string
+	// Challenger challenges the nodes on a different chain than the majority, challenges are disabled when nil
+	Challenger *Challenger
+	// LatencyRecorder records the latency of the relays to the nodes, not recorded when nil
+	LatencyRecorder *LatencyRecorder
+	// ResultRecorder records the verdict on each node, not recorded when nil
+	ResultRecorder *NodeResultRecorder
 }
 
 // ChainCheckOptions is the struct of the data needed to perform a chain check
@@ -32,18 +81,25 @@ type ChainCheckOptions struct {
 	Data       string
 	ChainID    string
 	Path       string
+	// Method is the HTTP method of the relay, POST by default
+	Method string
+	// ResultKey is the path of the chain id on the response, "result" by default
+	ResultKey string
+	// Match is how the chain id is compared, exact by default
+	Match string
 }
 
 type nodeChainLog struct {
 	Node  *provider.Node
-	Chain int64
+	Chain string
+	Relay *relayer.Output
 }
 
 // Check Performs a chain check of all the nodes of the given session
 func (cc *ChainChecker) Check(ctx context.Context, options ChainCheckOptions) []string {
 	checkedNodes := []string{}
 
-	chainID, err := strconv.Atoi(options.ChainID)
+	isExpectedChain, err := newChainIDMatcher(options.ChainID, options.Match)
 	if err != nil {
 		logger.Log.WithFields(log.Fields{
 			"sessionKey":   options.Session.Key,
@@ -59,7 +115,7 @@ func (cc *ChainChecker) Check(ctx context.Context, options ChainCheckOptions) []
 		publicKey := node.Node.PublicKey
 		nodeChainID := node.Chain
 
-		if nodeChainID != int64(chainID) {
+		if nodeChainID == "" || !isExpectedChain(nodeChainID) {
 			logger.Log.WithFields(log.Fields{
 				"sessionKey":            options.Session.Key,
 				"blockchainID":          options.Blockchain,
@@ -68,7 +124,7 @@ func (cc *ChainChecker) Check(ctx context.Context, options ChainCheckOptions) []
 				"serviceDomain":         utils.GetDomainFromURL(node.Node.ServiceURL),
 				"serviceNode":           node.Node.PublicKey,
 				"appplicationPublicKey": options.Session.Header.AppPublicKey,
-			}).Warn(fmt.Sprintf("CHAIN CHECK FAILURE: %s chainiD: %d", publicKey, nodeChainID))
+			}).Warn(fmt.Sprintf("CHAIN CHECK FAILURE: %s chainiD: %s", publicKey, nodeChainID))
 			continue
 		}
 
@@ -80,7 +136,7 @@ func (cc *ChainChecker) Check(ctx context.Context, options ChainCheckOptions) []
 			"serviceDomain":         utils.GetDomainFromURL(node.Node.ServiceURL),
 			"serviceNode":           node.Node.PublicKey,
 			"appplicationPublicKey": options.Session.Header.AppPublicKey,
-		}).Info(fmt.Sprintf("CHAIN CHECK SUCCESS: %s chainiD: %d", publicKey, nodeChainID))
+		}).Info(fmt.Sprintf("CHAIN CHECK SUCCESS: %s chainiD: %s", publicKey, nodeChainID))
 
 		checkedNodes = append(checkedNodes, publicKey)
 	}
@@ -92,11 +148,64 @@ func (cc *ChainChecker) Check(ctx context.Context, options ChainCheckOptions) []
 		"appplicationPublicKey": options.Session.Header.AppPublicKey,
 	}).Info(fmt.Sprintf("CHAIN CHECK COMPLETE: %d nodes on chain", len(checkedNodes)))
 
-	// TODO: Implement challenge
+	if cc.Challenger != nil && len(checkedNodes) < len(nodeLogs) {
+		cc.challenge(ctx, nodeLogs, checkedNodes, &options)
+	}
+
+	cc.recordResults(nodeLogs, checkedNodes, &options)
 
 	return checkedNodes
 }
 
+// recordResults records the verdict and chain id of every node of the session
+func (cc *ChainChecker) recordResults(nodeLogs []*nodeChainLog, checkedNodes []string, options *ChainCheckOptions) {
+	if cc.ResultRecorder == nil {
+		return
+	}
+
+	passed := make(map[string]bool, len(checkedNodes))
+	for _, publicKey := range checkedNodes {
+		passed[publicKey] = true
+	}
+
+	for _, node := range nodeLogs {
+		cc.ResultRecorder.Record(&options.Session, ChainCheckName, options.Blockchain, node.Node,
+			passed[node.Node.PublicKey], node.Chain)
+	}
+}
+
+// challenge submits a challenge against the nodes on the wrong chain, with the chain ids
+// of the nodes on the expected chain as evidence
+func (cc *ChainChecker) challenge(ctx context.Context, nodeLogs []*nodeChainLog, checkedNodes []string, options *ChainCheckOptions) {
+	passed := make(map[string]bool, len(checkedNodes))
+	for _, publicKey := range checkedNodes {
+		passed[publicKey] = true
+	}
+
+	majority, minority := []*NodeResponse{}, []*NodeResponse{}
+	for _, node := range nodeLogs {
+		response := &NodeResponse{
+			Node:   node.Node,
+			Output: node.Relay,
+			Value:  node.Chain,
+		}
+
+		if passed[node.Node.PublicKey] {
+			majority = append(majority, response)
+		} else {
+			minority = append(minority, response)
+		}
+	}
+
+	cc.Challenger.Challenge(ctx, ChallengeOptions{
+		Session:    options.Session,
+		Blockchain: options.Blockchain,
+		Check:      ChainCheckName,
+		Majority:   majority,
+		Minority:   minority,
+	})
+}
+
 func (cc *ChainChecker) getNodeChainLogs(ctx context.Context, options *ChainCheckOptions) []*nodeChainLog {
 	nodeLogsChan := make(chan *nodeChainLog, len(options.Session.Nodes))
 	nodeLogs := []*nodeChainLog{}
@@ -123,15 +232,25 @@ func (cc *ChainChecker) getNodeChainLogs(ctx context.Context, options *ChainChec
 func (cc *ChainChecker) getNodeChainLog(ctx context.Context, node *provider.Node, nodeLogs chan<- *nodeChainLog, options *ChainCheckOptions) {
 	start := time.Now()
 
-	chain, err := utils.GetIntFromRelay(*cc.Relayer, relayer.Input{
+	method := options.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	resultKey := options.ResultKey
+	if resultKey == "" {
+		resultKey = defaultChainIDResultKey
+	}
+
+	chain, relay, err := utils.GetParsedStringFromRelay(*cc.Relayer, relayer.Input{
 		Blockchain: options.Blockchain,
 		Data:       strings.Replace(options.Data, `\`, "", -1),
-		Method:     http.MethodPost,
+		Method:     method,
 		PocketAAT:  &options.PocketAAT,
 		Session:    &options.Session,
 		Node:       node,
 		Path:       options.Path,
-	}, "result")
+	}, resultKey, getChainIDExtractor(options.Blockchain))
+	cc.LatencyRecorder.Record(&options.Session, options.Blockchain, node, time.Since(start), err == nil)
 	if err != nil {
 		logger.Log.WithFields(log.Fields{
 			"sessionKey":    options.Session.Key,
@@ -157,7 +276,8 @@ func (cc *ChainChecker) getNodeChainLog(ctx context.Context, node *provider.Node
 
 		nodeLogs <- &nodeChainLog{
 			Node:  node,
-			Chain: 0,
+			Chain: "",
+			Relay: relay,
 		}
 		return
 	}
@@ -165,5 +285,120 @@ func (cc *ChainChecker) getNodeChainLog(ctx context.Context, node *provider.Node
 	nodeLogs <- &nodeChainLog{
 		Node:  node,
 		Chain: chain,
+		Relay: relay,
 	}
 }
+
+// newChainIDMatcher returns a function telling whether a chain id is the expected one
+func newChainIDMatcher(expectedChainID, match string) (func(chainID string) bool, error) {
+	switch match {
+	case "", ChainIDMatchExact:
+		expectedInteger, err := utils.ParseBigInteger(expectedChainID)
+		return func(chainID string) bool {
+			if chainID == expectedChainID {
+				return true
+			}
+			if err != nil {
+				return false
+			}
+
+			integer, err := utils.ParseBigInteger(chainID)
+			return err == nil && integer.Cmp(expectedInteger) == 0
+		}, nil
+	case ChainIDMatchPrefix:
+		return func(chainID string) bool {
+			return strings.HasPrefix(chainID, expectedChainID)
+		}, nil
+	case ChainIDMatchRegex:
+		// Anchored so the pattern has to match the whole chain id
+		regex, err := regexp.Compile(`^(?:` + expectedChainID + `)$`)
+		if err != nil {
+			return nil, errors.New("invalid chain id regex: " + err.Error())
+		}
+		return regex.MatchString, nil
+	}
+
+	return nil, fmt.Errorf("invalid chain id match %s", match)
+}
+
+// chainCheck is the Check implementation of the chain checker
+type chainCheck struct {
+	checker *ChainChecker
+	deps    *CheckDependencies
+	chains  map[string]*ChainIDCheckOptions
+}
+
+func newChainCheck(deps *CheckDependencies) (Check, error) {
+	chains := map[string]*ChainIDCheckOptions{}
+	if chainCheckChains != "" {
+		if err := json.Unmarshal([]byte(chainCheckChains), &chains); err != nil {
+			return nil, errors.New("error parsing chain check chains: " + err.Error())
+		}
+	}
+
+	return &chainCheck{
+		checker: &ChainChecker{
+			Relayer:         deps.Relayer,
+			MetricsRecorder: deps.MetricsRecorder,
+			RequestID:       deps.RequestID,
+			Challenger:      deps.Challenger,
+			LatencyRecorder: deps.LatencyRecorder,
+			ResultRecorder:  deps.ResultRecorder,
+		},
+		deps:   deps,
+		chains: chains,
+	}, nil
+}
+
+func (c *chainCheck) Name() string {
+	return ChainCheckName
+}
+
+func (c *chainCheck) IsApplicable(blockchain *types.Blockchain) bool {
+	options := c.checkOptions(blockchain)
+	return options.Data != "" || options.Method == http.MethodGet
+}
+
+func (c *chainCheck) Run(ctx context.Context, options *CheckOptions) []string {
+	checkOptions := c.checkOptions(&options.Blockchain)
+	checkOptions.Session = options.Session
+	checkOptions.PocketAAT = options.PocketAAT
+
+	return c.checker.Check(ctx, checkOptions)
+}
+
+// checkOptions returns the chain check options of the blockchain with its overrides applied
+func (c *chainCheck) checkOptions(blockchain *types.Blockchain) ChainCheckOptions {
+	options := ChainCheckOptions{
+		Blockchain: blockchain.ID,
+		Data:       blockchain.ChainIDCheck,
+		ChainID:    blockchain.ChainID,
+		Path:       blockchain.Path,
+	}
+
+	override, ok := c.chains[blockchain.ID]
+	if !ok || override == nil {
+		return options
+	}
+
+	options.Method = override.Method
+	options.ResultKey = override.ResultKey
+	options.Match = override.Match
+	if override.Body != "" {
+		options.Data = override.Body
+	}
+	if override.Path != "" {
+		options.Path = override.Path
+	}
+	if override.ChainID != "" {
+		options.ChainID = override.ChainID
+	}
+
+	return options
+}
+
+func (c *chainCheck) CachePolicy(sessionKey string) CachePolicy {
+	policy := newCachePolicy(c.deps, chainCheckKeyPrefix, sessionKey)
+	policy.StickyPenalties = true
+	return policy
+}
@@ -0,0 +1,89 @@
+package pocket
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pokt-foundation/portal-db/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChainIDMatcher(t *testing.T) {
+	c := require.New(t)
+
+	isExpectedChain, err := newChainIDMatcher("100", "")
+	c.NoError(err)
+	c.True(isExpectedChain("100"))
+	c.True(isExpectedChain("0x64"))
+	c.False(isExpectedChain("0x65"))
+	c.False(isExpectedChain("osmosis-1"))
+
+	isExpectedChain, err = newChainIDMatcher("osmosis-1", ChainIDMatchExact)
+	c.NoError(err)
+	c.True(isExpectedChain("osmosis-1"))
+	c.False(isExpectedChain("osmosis-2"))
+
+	isExpectedChain, err = newChainIDMatcher("osmosis-", ChainIDMatchPrefix)
+	c.NoError(err)
+	c.True(isExpectedChain("osmosis-1"))
+	c.False(isExpectedChain("cosmoshub-4"))
+
+	isExpectedChain, err = newChainIDMatcher(`^cosmoshub-\d+$`, ChainIDMatchRegex)
+	c.NoError(err)
+	c.True(isExpectedChain("cosmoshub-4"))
+	c.False(isExpectedChain("cosmoshub-testnet"))
+
+	// Patterns match the whole chain id even when they're not anchored
+	isExpectedChain, err = newChainIDMatcher(`osmosis-1`, ChainIDMatchRegex)
+	c.NoError(err)
+	c.True(isExpectedChain("osmosis-1"))
+	c.False(isExpectedChain("evil-osmosis-1x"))
+
+	isExpectedChain, err = newChainIDMatcher(`osmosis-1|juno-1`, ChainIDMatchRegex)
+	c.NoError(err)
+	c.True(isExpectedChain("juno-1"))
+	c.False(isExpectedChain("osmosis-12"))
+
+	_, err = newChainIDMatcher(`(`, ChainIDMatchRegex)
+	c.Error(err)
+
+	_, err = newChainIDMatcher("1", "contains")
+	c.EqualError(err, "invalid chain id match contains")
+}
+
+func TestChainCheck_checkOptions(t *testing.T) {
+	c := require.New(t)
+
+	check := &chainCheck{
+		chains: map[string]*ChainIDCheckOptions{
+			"0003": {
+				Method:    http.MethodGet,
+				Path:      "/node_info",
+				ResultKey: "node_info.network",
+				ChainID:   "osmosis-",
+				Match:     ChainIDMatchPrefix,
+			},
+		},
+	}
+
+	evm := &types.Blockchain{ID: "0021", ChainIDCheck: `{"method":"eth_chainId"}`, ChainID: "1"}
+	c.True(check.IsApplicable(evm))
+	c.Equal(ChainCheckOptions{
+		Blockchain: "0021",
+		Data:       `{"method":"eth_chainId"}`,
+		ChainID:    "1",
+	}, check.checkOptions(evm))
+
+	cosmos := &types.Blockchain{ID: "0003"}
+	c.True(check.IsApplicable(cosmos))
+	c.Equal(ChainCheckOptions{
+		Blockchain: "0003",
+		ChainID:    "osmosis-",
+		Path:       "/node_info",
+		Method:     http.MethodGet,
+		ResultKey:  "node_info.network",
+		Match:      ChainIDMatchPrefix,
+	}, check.checkOptions(cosmos))
+
+	c.False(check.IsApplicable(&types.Blockchain{ID: "0004"}))
+}
@@ -0,0 +1,261 @@
+package pocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	"github.com/Pocket/global-services/shared/metrics"
+	"github.com/Pocket/global-services/shared/utils"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-foundation/pocket-go/relayer"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMinMajorityResponses = 2
+	defaultMaxConcurrency       = 3
+	challengeMethod             = "challenge"
+)
+
+var (
+	// ErrNoChallengeMajority when not enough nodes that passed the check agree on a value to challenge against
+	ErrNoChallengeMajority = errors.New("no majority of equal responses to challenge against")
+)
+
+// NodeResponse is the relay response of a single node of a session
+type NodeResponse struct {
+	Node   *provider.Node
+	Output *relayer.Output
+	// Value is the value parsed from the response the check judged the node on
+	Value string
+}
+
+// relayResponse is a signed relay response as the network expects it on a challenge
+type relayResponse struct {
+	Signature string               `json:"signature"`
+	Payload   string               `json:"payload"`
+	Proof     *provider.RelayProof `json:"proof"`
+}
+
+type challengeInput struct {
+	MajorityResponses []*relayResponse `json:"majority_responses"`
+	MinorityResponse  *relayResponse   `json:"minority_response"`
+	ReporterAddress   string           `json:"address"`
+}
+
+// Challenger submits challenges against the nodes that failed a check, backed by the
+// responses of the nodes of their session that passed it
+type Challenger struct {
+	// ReporterAddress is the address of the account reporting the challenges
+	ReporterAddress string
+	// MinMajorityResponses is the minimum amount of equal responses needed to challenge, defaults to 2
+	MinMajorityResponses int
+	// MaxConcurrency is the maximum amount of challenges submitted at the same time, defaults to 3
+	MaxConcurrency  int
+	MetricsRecorder *metrics.Recorder
+	RequestID       string
+}
+
+// ChallengeOptions is the struct of the data needed to challenge the nodes of a session
+type ChallengeOptions struct {
+	Session    provider.Session
+	Blockchain string
+	// Check is the name of the check that found the conflicting responses
+	Check string
+	// Majority are the responses of the nodes that passed the check, used as evidence
+	Majority []*NodeResponse
+	// Minority are the responses of the nodes that failed the check, the ones challenged
+	Minority []*NodeResponse
+}
+
+// ChallengeResult is the outcome of challenging a single node
+type ChallengeResult struct {
+	NodePublicKey string
+	Submitted     bool
+	Error         error
+}
+
+// Challenge submits a challenge against every node that failed the check, using as evidence the
+// most common value among the nodes that passed it. Returns the result of every challenged node.
+func (c *Challenger) Challenge(ctx context.Context, options ChallengeOptions) ([]*ChallengeResult, error) {
+	majority, err := c.majorityResponses(options.Majority)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"sessionKey":   options.Session.Key,
+			"blockchainID": options.Blockchain,
+			"requestID":    c.RequestID,
+			"check":        options.Check,
+		}).Warn("challenge: " + err.Error())
+		return nil, err
+	}
+
+	majorityResponses := make([]*relayResponse, 0, len(majority))
+	for _, response := range majority[:c.minMajorityResponses()] {
+		majorityResponses = append(majorityResponses, newRelayResponse(response.Output))
+	}
+
+	minority := []*NodeResponse{}
+	for _, response := range options.Minority {
+		// Nodes answering the same as the evidence can't be challenged over it
+		if isChallengeable(response) && response.Value != majority[0].Value {
+			minority = append(minority, response)
+		}
+	}
+
+	results := make([]*ChallengeResult, len(minority))
+	sem := make(chan struct{}, c.maxConcurrency())
+	var wg sync.WaitGroup
+	for idx, response := range minority {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int, response *NodeResponse) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Challenges are reported to a node of the majority, rotating between them
+			reportNode := majority[idx%len(majority)].Node
+
+			start := time.Now()
+			err := submitChallenge(reportNode.ServiceURL, &challengeInput{
+				MajorityResponses: majorityResponses,
+				MinorityResponse:  newRelayResponse(response.Output),
+				ReporterAddress:   c.ReporterAddress,
+			})
+
+			results[idx] = &ChallengeResult{
+				NodePublicKey: response.Node.PublicKey,
+				Submitted:     err == nil,
+				Error:         err,
+			}
+
+			c.recordChallenge(ctx, &options, response.Node, results[idx], time.Since(start))
+		}(idx, response)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// majorityResponses returns the responses sharing the most common value, ties are broken by
+// the first value seen. Responses without a relay output are ignored as they can't be used.
+func (c *Challenger) majorityResponses(responses []*NodeResponse) ([]*NodeResponse, error) {
+	groups := make(map[string][]*NodeResponse)
+	values := []string{}
+	for _, response := range responses {
+		if !isChallengeable(response) {
+			continue
+		}
+
+		if _, ok := groups[response.Value]; !ok {
+			values = append(values, response.Value)
+		}
+		groups[response.Value] = append(groups[response.Value], response)
+	}
+
+	majorityValue, majorityCount := "", 0
+	for _, value := range values {
+		if count := len(groups[value]); count > majorityCount {
+			majorityValue, majorityCount = value, count
+		}
+	}
+
+	if majorityCount < c.minMajorityResponses() {
+		return nil, ErrNoChallengeMajority
+	}
+
+	return groups[majorityValue], nil
+}
+
+func isChallengeable(response *NodeResponse) bool {
+	return response != nil && response.Output != nil && response.Output.RelayOutput != nil
+}
+
+func (c *Challenger) maxConcurrency() int {
+	if c.MaxConcurrency <= 0 {
+		return defaultMaxConcurrency
+	}
+	return c.MaxConcurrency
+}
+
+func (c *Challenger) minMajorityResponses() int {
+	if c.MinMajorityResponses <= 0 {
+		return defaultMinMajorityResponses
+	}
+	return c.MinMajorityResponses
+}
+
+func (c *Challenger) recordChallenge(ctx context.Context, options *ChallengeOptions, node *provider.Node, result *ChallengeResult, elapsed time.Duration) {
+	message := fmt.Sprintf("%s challenge submitted", options.Check)
+	fields := log.Fields{
+		"sessionKey":            options.Session.Key,
+		"blockchainID":          options.Blockchain,
+		"requestID":             c.RequestID,
+		"serviceURL":            node.ServiceURL,
+		"serviceDomain":         utils.GetDomainFromURL(node.ServiceURL),
+		"serviceNode":           node.PublicKey,
+		"appplicationPublicKey": options.Session.Header.AppPublicKey,
+	}
+
+	if result.Error != nil {
+		message = fmt.Sprintf("%s challenge failed: %s", options.Check, result.Error.Error())
+		fields["error"] = result.Error.Error()
+		logger.Log.WithFields(fields).Error("CHALLENGE FAILURE: " + node.PublicKey)
+	} else {
+		logger.Log.WithFields(fields).Info("CHALLENGE SUBMITTED: " + node.PublicKey)
+	}
+
+	if c.MetricsRecorder == nil {
+		return
+	}
+
+	c.MetricsRecorder.WriteErrorMetric(ctx, &metrics.Metric{
+		Timestamp:            time.Now(),
+		ApplicationPublicKey: options.Session.Header.AppPublicKey,
+		Blockchain:           options.Blockchain,
+		NodePublicKey:        node.PublicKey,
+		ElapsedTime:          elapsed.Seconds(),
+		Bytes:                len(message),
+		Method:               challengeMethod,
+		Message:              message,
+		RequestID:            c.RequestID,
+	})
+}
+
+func newRelayResponse(output *relayer.Output) *relayResponse {
+	return &relayResponse{
+		Signature: output.RelayOutput.Signature,
+		Payload:   output.RelayOutput.Response,
+		Proof:     output.Proof,
+	}
+}
+
+func submitChallenge(serviceURL string, challenge *challengeInput) error {
+	body, err := json.Marshal(challenge)
+	if err != nil {
+		return errors.New("error marshalling challenge: " + err.Error())
+	}
+
+	headers := http.Header{}
+	headers.Add("Content-Type", "application/json")
+
+	res, err := httpClient.Post(serviceURL+string(provider.ClientChallengeRoute), bytes.NewBuffer(body), headers)
+	defer utils.CloseOrLog(res)
+	if err != nil {
+		return errors.New("error submitting challenge: " + err.Error())
+	}
+
+	if res.StatusCode != http.StatusOK {
+		response, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("challenge rejected with status %d: %s", res.StatusCode, string(response))
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package pocket
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-foundation/pocket-go/relayer"
+	"github.com/pokt-foundation/utils-go/mock-client"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNodeResponse(publicKey, payload, value string) *NodeResponse {
+	return &NodeResponse{
+		Value: value,
+		Node: &provider.Node{
+			PublicKey:  publicKey,
+			ServiceURL: "https://" + publicKey + ".com",
+		},
+		Output: &relayer.Output{
+			RelayOutput: &provider.RelayOutput{Response: payload, Signature: "signature"},
+			Proof:       &provider.RelayProof{ServicerPubKey: publicKey},
+		},
+	}
+}
+
+func TestChallenger_Challenge(t *testing.T) {
+	c := require.New(t)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	mock.AddMockedResponse(http.MethodPost, "https://node1.com/v1/client/challenge", http.StatusOK, `{"response":"ok"}`)
+	mock.AddMockedResponse(http.MethodPost, "https://node2.com/v1/client/challenge", http.StatusBadRequest, `{"error":"invalid"}`)
+
+	challenger := &Challenger{ReporterAddress: "reporter"}
+	options := ChallengeOptions{
+		Session: provider.Session{
+			Key:    "session-key",
+			Header: &provider.SessionHeader{AppPublicKey: "app"},
+		},
+		Blockchain: "0021",
+		Check:      SyncCheckName,
+		// Synced nodes with different heights are never challenged
+		Majority: []*NodeResponse{
+			newTestNodeResponse("node1", `{"result":"0x10"}`, "16"),
+			newTestNodeResponse("node2", `{"id":2,"result":"0x10"}`, "16"),
+			newTestNodeResponse("node6", `{"result":"0x11"}`, "17"),
+		},
+		Minority: []*NodeResponse{
+			newTestNodeResponse("node3", `{"result":"0x01"}`, "1"),
+			newTestNodeResponse("node4", `{"result":"0x02"}`, "2"),
+			newTestNodeResponse("node7", `{"result":"0x10"}`, "16"),
+			{Node: &provider.Node{PublicKey: "node5"}},
+		},
+	}
+
+	results, err := challenger.Challenge(context.Background(), options)
+	c.NoError(err)
+	c.Len(results, 2)
+
+	c.Equal("node3", results[0].NodePublicKey)
+	c.True(results[0].Submitted)
+	// Reported to the second node of the majority which rejects the challenge
+	c.Equal("node4", results[1].NodePublicKey)
+	c.False(results[1].Submitted)
+	c.Error(results[1].Error)
+
+	options.Majority = options.Majority[1:]
+	_, err = challenger.Challenge(context.Background(), options)
+	c.ErrorIs(err, ErrNoChallengeMajority)
+}
@@ -0,0 +1,134 @@
+package pocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Pocket/global-services/shared/metrics"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-foundation/pocket-go/relayer"
+	"github.com/pokt-foundation/portal-db/types"
+)
+
+const defaultEmptyNodesTTL = 30 * time.Second
+
+// Check is a QoS check performed on the nodes of a session. Checks register themselves
+// with RegisterCheck so they're run by the fishermen without any further wiring.
+type Check interface {
+	// Name identifies the check, must be unique among the registered checks
+	Name() string
+	// IsApplicable returns whether the check can be performed on the nodes of the blockchain
+	IsApplicable(blockchain *types.Blockchain) bool
+	// Run performs the check, returning the public keys of the nodes that passed it
+	Run(ctx context.Context, options *CheckOptions) []string
+	// CachePolicy returns how the results of the check for the given session are cached
+	CachePolicy(sessionKey string) CachePolicy
+}
+
+// CheckOptions is the data of a session needed to run a check on its nodes
+type CheckOptions struct {
+	Session    provider.Session
+	PocketAAT  provider.PocketAAT
+	Blockchain types.Blockchain
+}
+
+// CachePolicy defines where and for how long the results of a check are cached
+type CachePolicy struct {
+	Key string
+	TTL time.Duration
+	// EmptyTTL is the ttl used when no node passed the check
+	EmptyTTL time.Duration
+	// EraseFailureMarks clears the failure mark of the nodes that passed the check
+	EraseFailureMarks bool
+	// StickyPenalties excludes the nodes penalized by their check history even if they passed
+	StickyPenalties bool
+}
+
+// CheckedWithin returns whether the results cached with the policy were written less than the
+// interval ago, given the ttl the cached results have left. Empty results are never recent
+// since they're cached for a short time only to avoid checking the nodes constantly.
+func (p CachePolicy) CheckedWithin(cachedNodes string, remainingTTL, interval time.Duration) bool {
+	if remainingTTL <= 0 || interval <= 0 {
+		return false
+	}
+
+	nodes := []string{}
+	if err := json.Unmarshal([]byte(cachedNodes), &nodes); err != nil || len(nodes) == 0 {
+		return false
+	}
+
+	return p.TTL-remainingTTL < interval
+}
+
+// CheckDependencies are the clients and settings shared by all the checks
+type CheckDependencies struct {
+	Relayer                *relayer.Relayer
+	MetricsRecorder        *metrics.Recorder
+	Challenger             *Challenger
+	LatencyRecorder        *LatencyRecorder
+	ResultRecorder         *NodeResultRecorder
+	RequestID              string
+	CommitHash             string
+	CacheTTL               time.Duration
+	DefaultSyncAllowance   int
+	AltruistTrustThreshold float32
+	AltruistMinValidNodes  int
+}
+
+// CheckFactory builds a check from the dependencies given
+type CheckFactory func(deps *CheckDependencies) (Check, error)
+
+type registeredCheck struct {
+	name    string
+	factory CheckFactory
+}
+
+var registeredChecks []registeredCheck
+
+// RegisterCheck registers a check to be run by the fishermen, meant to be called on init
+func RegisterCheck(name string, factory CheckFactory) {
+	for _, check := range registeredChecks {
+		if check.name == name {
+			panic(fmt.Sprintf("check %s already registered", name))
+		}
+	}
+
+	registeredChecks = append(registeredChecks, registeredCheck{name: name, factory: factory})
+}
+
+// NewChecks returns an instance of all the registered checks
+func NewChecks(deps *CheckDependencies) ([]Check, error) {
+	checks := make([]Check, 0, len(registeredChecks))
+	for _, registered := range registeredChecks {
+		check, err := registered.factory(deps)
+		if err != nil {
+			return nil, fmt.Errorf("error creating check %s: %s", registered.name, err.Error())
+		}
+		checks = append(checks, check)
+	}
+
+	return checks, nil
+}
+
+// ApplicableChecks returns only the checks that can be performed on the blockchain
+func ApplicableChecks(checks []Check, blockchain *types.Blockchain) []Check {
+	applicable := []Check{}
+	for _, check := range checks {
+		if check.IsApplicable(blockchain) {
+			applicable = append(applicable, check)
+		}
+	}
+
+	return applicable
+}
+
+// newCachePolicy returns the cache policy most checks use, with the results keyed by session
+func newCachePolicy(deps *CheckDependencies, keyPrefix, sessionKey string) CachePolicy {
+	return CachePolicy{
+		Key:      deps.CommitHash + keyPrefix + sessionKey,
+		TTL:      deps.CacheTTL,
+		EmptyTTL: defaultEmptyNodesTTL,
+	}
+}
@@ -0,0 +1,54 @@
+package pocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pokt-foundation/portal-db/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChecks(t *testing.T) {
+	c := require.New(t)
+
+	checks, err := NewChecks(&CheckDependencies{CacheTTL: time.Minute})
+	c.NoError(err)
+
+	names := []string{}
+	for _, check := range checks {
+		names = append(names, check.Name())
+	}
+	c.Contains(names, SyncCheckName)
+	c.Contains(names, ChainCheckName)
+
+	applicable := ApplicableChecks(checks, &types.Blockchain{ChainIDCheck: `{"method":"eth_chainId"}`})
+	c.Len(applicable, 1)
+	c.Equal(ChainCheckName, applicable[0].Name())
+
+	policy := applicable[0].CachePolicy("session-key")
+	c.Equal("chain-check-session-key", policy.Key)
+	c.Equal(time.Minute, policy.TTL)
+	c.Equal(defaultEmptyNodesTTL, policy.EmptyTTL)
+	c.False(policy.EraseFailureMarks)
+}
+
+func TestRegisterCheck_Duplicated(t *testing.T) {
+	c := require.New(t)
+
+	c.Panics(func() {
+		RegisterCheck(SyncCheckName, newSyncCheck)
+	})
+}
+
+func TestCachePolicy_CheckedWithin(t *testing.T) {
+	c := require.New(t)
+
+	policy := CachePolicy{TTL: 5 * time.Minute}
+
+	c.True(policy.CheckedWithin(`["node1"]`, 4*time.Minute, 2*time.Minute))
+	c.False(policy.CheckedWithin(`["node1"]`, 2*time.Minute, 2*time.Minute))
+	c.False(policy.CheckedWithin(`[]`, 4*time.Minute, 2*time.Minute))
+	c.False(policy.CheckedWithin("", 4*time.Minute, 2*time.Minute))
+	c.False(policy.CheckedWithin(`["node1"]`, -2, 2*time.Minute))
+	c.False(policy.CheckedWithin(`["node1"]`, 4*time.Minute, 0))
+}
@@ -0,0 +1,188 @@
+package pocket
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pocket/global-services/shared/utils"
+	"github.com/pokt-foundation/pocket-go/provider"
+)
+
+var (
+	// ErrLessThanMinimumNodes when the session of an app can't be dispatched due to missing nodes
+	ErrLessThanMinimumNodes = errors.New("there are less than the minimum session nodes found")
+	// ErrNoDispatchers when the pool doesn't have any dispatcher to use
+	ErrNoDispatchers = errors.New("no dispatchers available")
+)
+
+// IsLessThanMinimumNodesError returns whether the dispatch error is due to the
+// session not having enough nodes, such sessions cannot be dispatched so is not an actual error
+func IsLessThanMinimumNodesError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), ErrLessThanMinimumNodes.Error())
+}
+
+// DispatcherStats is the accounting of all the calls made to a single dispatcher
+type DispatcherStats struct {
+	URL               string  `json:"url"`
+	Successes         int     `json:"successes"`
+	Errors            int     `json:"errors"`
+	AvgLatencySeconds float64 `json:"avgLatencySeconds"`
+	CircuitBroken     bool    `json:"circuitBroken"`
+}
+
+type dispatcher struct {
+	url               string
+	provider          *provider.Provider
+	mu                sync.Mutex
+	successes         int
+	errors            int
+	consecutiveErrors int
+	totalLatency      time.Duration
+	circuitBroken     bool
+}
+
+func (d *dispatcher) record(latency time.Duration, err error, maxConsecutiveErrors int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.totalLatency += latency
+
+	// The dispatcher answered correctly, the session just can't be dispatched
+	if err == nil || IsLessThanMinimumNodesError(err) {
+		d.successes++
+		d.consecutiveErrors = 0
+		return
+	}
+
+	d.errors++
+	d.consecutiveErrors++
+	if maxConsecutiveErrors > 0 && d.consecutiveErrors >= maxConsecutiveErrors {
+		d.circuitBroken = true
+	}
+}
+
+func (d *dispatcher) isCircuitBroken() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.circuitBroken
+}
+
+func (d *dispatcher) stats() *DispatcherStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := &DispatcherStats{
+		URL:           d.url,
+		Successes:     d.successes,
+		Errors:        d.errors,
+		CircuitBroken: d.circuitBroken,
+	}
+	if calls := d.successes + d.errors; calls > 0 {
+		stats.AvgLatencySeconds = d.totalLatency.Seconds() / float64(calls)
+	}
+
+	return stats
+}
+
+// DispatcherPoolOptions is the config of a dispatcher pool
+type DispatcherPoolOptions struct {
+	RPCURL       string
+	DispatchURLs []string
+	// MaxConsecutiveErrors is the amount of errors in a row after which a dispatcher
+	// is circuit broken, zero disables the circuit breaker
+	MaxConsecutiveErrors int
+	// MaxAttempts is the amount of dispatchers tried for a single dispatch
+	MaxAttempts int
+	// Timeout is the timeout of each dispatch request, the provider's default is kept when zero
+	Timeout time.Duration
+}
+
+// DispatcherPool dispatches sessions through a set of dispatchers, keeping the
+// health of each one of them and failing over to another dispatcher on errors.
+// Circuit broken dispatchers are only used once there are no healthy ones left.
+type DispatcherPool struct {
+	dispatchers          []*dispatcher
+	maxConsecutiveErrors int
+	maxAttempts          int
+}
+
+// NewDispatcherPool returns a dispatcher pool with a provider for every dispatch url
+func NewDispatcherPool(options DispatcherPoolOptions) *DispatcherPool {
+	dispatchers := []*dispatcher{}
+	for _, url := range options.DispatchURLs {
+		if url == "" {
+			continue
+		}
+
+		rpcProvider := provider.NewProvider(options.RPCURL, []string{url})
+		if options.Timeout > 0 {
+			rpcProvider.UpdateRequestConfig(0, options.Timeout)
+		}
+
+		dispatchers = append(dispatchers, &dispatcher{
+			url:      url,
+			provider: rpcProvider,
+		})
+	}
+
+	maxAttempts := options.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	return &DispatcherPool{
+		dispatchers:          dispatchers,
+		maxConsecutiveErrors: options.MaxConsecutiveErrors,
+		maxAttempts:          maxAttempts,
+	}
+}
+
+// Dispatch dispatches the session of the app and chain given, failing over to
+// other dispatchers if the one used fails
+func (p *DispatcherPool) Dispatch(appPublicKey, chain string, options *provider.DispatchRequestOptions) (*provider.DispatchOutput, error) {
+	candidates := p.candidates()
+	if len(candidates) == 0 {
+		return nil, ErrNoDispatchers
+	}
+
+	var err error
+	for _, candidate := range candidates[:utils.Min(len(candidates), p.maxAttempts)] {
+		start := time.Now()
+
+		var dispatch *provider.DispatchOutput
+		dispatch, err = candidate.provider.Dispatch(appPublicKey, chain, options)
+		candidate.record(time.Since(start), err, p.maxConsecutiveErrors)
+
+		if err == nil || IsLessThanMinimumNodesError(err) {
+			return dispatch, err
+		}
+	}
+
+	return nil, err
+}
+
+// Stats returns the accounting of all the dispatchers of the pool
+func (p *DispatcherPool) Stats() []*DispatcherStats {
+	stats := make([]*DispatcherStats, 0, len(p.dispatchers))
+	for _, d := range p.dispatchers {
+		stats = append(stats, d.stats())
+	}
+	return stats
+}
+
+// candidates returns the dispatchers in the order they should be tried, healthy
+// dispatchers come first in random order followed by the circuit broken ones
+func (p *DispatcherPool) candidates() []*dispatcher {
+	healthy, broken := []*dispatcher{}, []*dispatcher{}
+	for _, d := range utils.Shuffle(p.dispatchers) {
+		if d.isCircuitBroken() {
+			broken = append(broken, d)
+			continue
+		}
+		healthy = append(healthy, d)
+	}
+
+	return append(healthy, broken...)
+}
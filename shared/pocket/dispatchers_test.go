@@ -0,0 +1,60 @@
+package pocket
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/pokt-foundation/utils-go/mock-client"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testHealthyDispatcher = "https://healthy-dispatcher.com"
+	testFailingDispatcher = "https://failing-dispatcher.com"
+	dispatchEndpoint      = "/v1/client/dispatch"
+)
+
+func TestDispatcherPool_Dispatch(t *testing.T) {
+	c := require.New(t)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	mock.AddMockedResponse(http.MethodPost, testHealthyDispatcher+dispatchEndpoint, http.StatusOK,
+		`{"block_height":10,"session":{"key":"session-key","header":{"app_public_key":"app","chain":"0021","session_height":9},"nodes":[]}}`)
+	mock.AddMockedResponse(http.MethodPost, testFailingDispatcher+dispatchEndpoint, http.StatusInternalServerError, `{}`)
+
+	pool := NewDispatcherPool(DispatcherPoolOptions{
+		RPCURL:               testHealthyDispatcher,
+		DispatchURLs:         []string{testHealthyDispatcher, testFailingDispatcher},
+		MaxConsecutiveErrors: 1,
+		MaxAttempts:          2,
+	})
+
+	for i := 0; i < 5; i++ {
+		dispatch, err := pool.Dispatch("app", "0021", nil)
+		c.NoError(err)
+		c.Equal("session-key", dispatch.Session.Key)
+	}
+
+	stats := map[string]*DispatcherStats{}
+	for _, stat := range pool.Stats() {
+		stats[stat.URL] = stat
+	}
+
+	c.Equal(5, stats[testHealthyDispatcher].Successes)
+	c.False(stats[testHealthyDispatcher].CircuitBroken)
+	// Once circuit broken, the failing dispatcher is not used while there are healthy ones
+	c.LessOrEqual(stats[testFailingDispatcher].Errors, 1)
+	c.Zero(stats[testFailingDispatcher].Successes)
+}
+
+func TestDispatcherPool_NoDispatchers(t *testing.T) {
+	c := require.New(t)
+
+	pool := NewDispatcherPool(DispatcherPoolOptions{DispatchURLs: []string{""}})
+
+	_, err := pool.Dispatch("app", "0021", nil)
+	c.ErrorIs(err, ErrNoDispatchers)
+}
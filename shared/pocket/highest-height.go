@@ -0,0 +1,111 @@
+package pocket
+
+import (
+	"errors"
+	"sort"
+)
+
+// Methods to obtain the highest block height of the nodes of a session
+const (
+	// HighestHeightMAD discards the heights further than a multiple of the median absolute
+	// deviation above the median
+	HighestHeightMAD = "mad"
+	// HighestHeightAgreement uses the highest height that at least MinAgreement nodes
+	// agree on, nodes agree when their heights are within the allowance
+	HighestHeightAgreement = "agreement"
+	// HighestHeightMax uses the highest height reported by any node, it's the default method
+	HighestHeightMax = "max"
+)
+
+// ErrNoHeightConsensus when not enough nodes agree on a height
+var ErrNoHeightConsensus = errors.New("no consensus on the highest block height")
+
+const (
+	defaultMADMultiplier = 3
+	defaultMinAgreement  = 2
+)
+
+// HighestHeightOptions configures how the highest block height of a session is obtained,
+// so a single node reporting a bogus height can't set the bar for the rest
+type HighestHeightOptions struct {
+	Method        string  `json:"method"`
+	MADMultiplier float64 `json:"madMultiplier"`
+	MinAgreement  int     `json:"minAgreement"`
+}
+
+// withDefaults fills the empty fields of the options with the defaults
+func (o HighestHeightOptions) withDefaults() HighestHeightOptions {
+	if o.Method == "" {
+		o.Method = HighestHeightMax
+	}
+	if o.MADMultiplier <= 0 {
+		o.MADMultiplier = defaultMADMultiplier
+	}
+	if o.MinAgreement <= 0 {
+		o.MinAgreement = defaultMinAgreement
+	}
+	return o
+}
+
+// getHighestBlockHeight returns the highest block height of the nodes that is not an outlier,
+// heights within the allowance of the median are never considered outliers. Returns
+// ErrNoHeightConsensus when the agreement method finds no height enough nodes agree on.
+func getHighestBlockHeight(heights []int64, allowance int64, options HighestHeightOptions) (int64, error) {
+	options = options.withDefaults()
+
+	valid := []int64{}
+	for _, height := range heights {
+		if height > 0 {
+			valid = append(valid, height)
+		}
+	}
+	if len(valid) == 0 {
+		return 0, nil
+	}
+
+	// Sorted from highest to lowest
+	sort.Slice(valid, func(i, j int) bool {
+		return valid[i] > valid[j]
+	})
+
+	switch options.Method {
+	case HighestHeightMax:
+		return valid[0], nil
+	case HighestHeightAgreement:
+		// Each height groups the ones within the allowance below it, the highest
+		// group with enough nodes sets the height
+		for i := range valid {
+			agreeing := 0
+			for j := i; j < len(valid) && valid[i]-valid[j] <= allowance; j++ {
+				agreeing++
+			}
+			if agreeing >= options.MinAgreement {
+				return valid[i], nil
+			}
+		}
+		return 0, ErrNoHeightConsensus
+	}
+
+	median := getMedianBlockHeight(valid)
+	deviations := make([]int64, 0, len(valid))
+	for _, height := range valid {
+		deviation := height - median
+		if deviation < 0 {
+			deviation = -deviation
+		}
+		deviations = append(deviations, deviation)
+	}
+
+	tolerance := int64(options.MADMultiplier * float64(getMedianBlockHeight(deviations)))
+	if tolerance < allowance {
+		tolerance = allowance
+	}
+
+	for _, height := range valid {
+		if height <= median+tolerance {
+			return height, nil
+		}
+	}
+
+	return median, nil
+}
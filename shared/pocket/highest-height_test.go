@@ -0,0 +1,55 @@
+package pocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHighestBlockHeight(t *testing.T) {
+	c := require.New(t)
+
+	heights := []int64{100, 0, 101, 99, 100, 9999999}
+
+	height, err := getHighestBlockHeight([]int64{0, -1}, 5, HighestHeightOptions{})
+	c.NoError(err)
+	c.Equal(int64(0), height)
+
+	// The highest height is used unless the chain opts into another method
+	height, err = getHighestBlockHeight(heights, 5, HighestHeightOptions{})
+	c.NoError(err)
+	c.Equal(int64(9999999), height)
+
+	// A single bogus height is discarded by the mad method
+	height, err = getHighestBlockHeight(heights, 5, HighestHeightOptions{Method: HighestHeightMAD})
+	c.NoError(err)
+	c.Equal(int64(101), height)
+	// Heights within the allowance are kept even with no deviation
+	height, err = getHighestBlockHeight([]int64{100, 100, 100, 104}, 5, HighestHeightOptions{Method: HighestHeightMAD})
+	c.NoError(err)
+	c.Equal(int64(104), height)
+	height, err = getHighestBlockHeight([]int64{100, 100, 100, 104}, 0, HighestHeightOptions{Method: HighestHeightMAD})
+	c.NoError(err)
+	c.Equal(int64(100), height)
+}
+
+func TestGetHighestBlockHeightAgreement(t *testing.T) {
+	c := require.New(t)
+
+	heights := []int64{100, 0, 101, 99, 100, 9999999}
+
+	height, err := getHighestBlockHeight(heights, 5, HighestHeightOptions{Method: HighestHeightAgreement})
+	c.NoError(err)
+	c.Equal(int64(101), height)
+
+	height, err = getHighestBlockHeight(heights, 0, HighestHeightOptions{Method: HighestHeightAgreement, MinAgreement: 2})
+	c.NoError(err)
+	c.Equal(int64(100), height)
+
+	// Nodes far apart don't agree even when there are enough of them
+	_, err = getHighestBlockHeight([]int64{100, 200, 300}, 5, HighestHeightOptions{Method: HighestHeightAgreement})
+	c.ErrorIs(err, ErrNoHeightConsensus)
+
+	_, err = getHighestBlockHeight(heights, 5, HighestHeightOptions{Method: HighestHeightAgreement, MinAgreement: 10})
+	c.ErrorIs(err, ErrNoHeightConsensus)
+}
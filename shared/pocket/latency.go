@@ -0,0 +1,168 @@
+package pocket
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	"github.com/pokt-foundation/pocket-go/provider"
+	log "github.com/sirupsen/logrus"
+)
+
+// NodeLatencyScore is the latency of a node measured by the checks of a session, it has the
+// same shape as the service logs of the gateway that the cherry picker uses
+type NodeLatencyScore struct {
+	NodePublicKey        string `json:"nodePublicKey"`
+	Chain                string `json:"chain"`
+	MedianSuccessLatency string `json:"medianSuccessLatency"`
+	SessionKey           string `json:"sessionKey"`
+	SessionHeight        int    `json:"sessionHeight"`
+	Metadata             struct {
+		P90                  float32 `json:"p90"`
+		Attempts             int     `json:"attempts"`
+		SuccessRate          float32 `json:"successRate"`
+		ApplicationPublicKey string  `json:"applicationPublicKey"`
+	} `json:"metadata"`
+}
+
+// NodeLatencyScoreKey returns the key the latency score of a node is cached on
+func NodeLatencyScoreKey(commitHash, chain, nodePublicKey string) string {
+	return fmt.Sprintf("%s{%s}-%s-check-latency", commitHash, chain, nodePublicKey)
+}
+
+type nodeLatencies struct {
+	successes []float64
+	failures  int
+}
+
+type sessionLatencies struct {
+	chain         string
+	appPublicKey  string
+	sessionHeight int
+	nodes         map[string]*nodeLatencies
+}
+
+// LatencyRecorder keeps the round-trip latency of the relays done by the checks to each
+// node of a session, safe for concurrent use. A nil recorder doesn't record anything.
+type LatencyRecorder struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionLatencies
+}
+
+// NewLatencyRecorder returns an empty latency recorder
+func NewLatencyRecorder() *LatencyRecorder {
+	return &LatencyRecorder{
+		sessions: make(map[string]*sessionLatencies),
+	}
+}
+
+// Record saves the latency of a relay to a node of the session, only successful
+// relays count towards the latency of the node
+func (lr *LatencyRecorder) Record(session *provider.Session, chain string, node *provider.Node, latency time.Duration, success bool) {
+	if lr == nil || session == nil || node == nil {
+		return
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	sessionLogs, ok := lr.sessions[session.Key]
+	if !ok {
+		sessionLogs = &sessionLatencies{
+			chain: chain,
+			nodes: make(map[string]*nodeLatencies),
+		}
+		if session.Header != nil {
+			sessionLogs.appPublicKey = session.Header.AppPublicKey
+			sessionLogs.sessionHeight = session.Header.SessionHeight
+		}
+		lr.sessions[session.Key] = sessionLogs
+	}
+
+	nodeLogs, ok := sessionLogs.nodes[node.PublicKey]
+	if !ok {
+		nodeLogs = &nodeLatencies{}
+		sessionLogs.nodes[node.PublicKey] = nodeLogs
+	}
+
+	if !success {
+		nodeLogs.failures++
+		return
+	}
+	nodeLogs.successes = append(nodeLogs.successes, latency.Seconds())
+}
+
+// Scores returns the latency score of every node of the session that had at least
+// one successful relay, the recorded latencies of the session are discarded
+func (lr *LatencyRecorder) Scores(sessionKey string) []*NodeLatencyScore {
+	if lr == nil {
+		return nil
+	}
+
+	lr.mu.Lock()
+	sessionLogs, ok := lr.sessions[sessionKey]
+	delete(lr.sessions, sessionKey)
+	lr.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	scores := []*NodeLatencyScore{}
+	allLatencies := []float64{}
+	for publicKey, nodeLogs := range sessionLogs.nodes {
+		if len(nodeLogs.successes) == 0 {
+			continue
+		}
+
+		latencies := append([]float64{}, nodeLogs.successes...)
+		sort.Float64s(latencies)
+		allLatencies = append(allLatencies, latencies...)
+
+		attempts := len(latencies) + nodeLogs.failures
+		score := &NodeLatencyScore{
+			NodePublicKey:        publicKey,
+			Chain:                sessionLogs.chain,
+			MedianSuccessLatency: strconv.FormatFloat(percentile(latencies, 50), 'f', 5, 32),
+			SessionKey:           sessionKey,
+			SessionHeight:        sessionLogs.sessionHeight,
+		}
+		score.Metadata.P90 = float32(percentile(latencies, 90))
+		score.Metadata.Attempts = attempts
+		score.Metadata.SuccessRate = float32(len(latencies)) / float32(attempts)
+		score.Metadata.ApplicationPublicKey = sessionLogs.appPublicKey
+
+		scores = append(scores, score)
+	}
+
+	if len(allLatencies) > 0 {
+		sort.Float64s(allLatencies)
+		logger.Log.WithFields(log.Fields{
+			"sessionKey":            sessionKey,
+			"blockchainID":          sessionLogs.chain,
+			"appplicationPublicKey": sessionLogs.appPublicKey,
+			"medianLatency":         percentile(allLatencies, 50),
+			"p90Latency":            percentile(allLatencies, 90),
+		}).Info(fmt.Sprintf("CHECKS LATENCY: %d nodes scored", len(scores)))
+	}
+
+	return scores
+}
+
+// percentile returns the nearest-rank percentile of the sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank]
+}
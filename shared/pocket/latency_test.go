@@ -0,0 +1,61 @@
+package pocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyRecorder_Scores(t *testing.T) {
+	c := require.New(t)
+
+	recorder := NewLatencyRecorder()
+	session := &provider.Session{
+		Key:    "session-key",
+		Header: &provider.SessionHeader{AppPublicKey: "app", SessionHeight: 100},
+	}
+	node1 := &provider.Node{PublicKey: "node1"}
+	node2 := &provider.Node{PublicKey: "node2"}
+	node3 := &provider.Node{PublicKey: "node3"}
+
+	for _, latency := range []time.Duration{300, 100, 200, 400} {
+		recorder.Record(session, "0021", node1, latency*time.Millisecond, true)
+	}
+	recorder.Record(session, "0021", node1, time.Second, false)
+	recorder.Record(session, "0021", node2, 50*time.Millisecond, true)
+	recorder.Record(session, "0021", node3, time.Second, false)
+
+	scores := recorder.Scores("session-key")
+	c.Len(scores, 2)
+
+	byNode := map[string]*NodeLatencyScore{}
+	for _, score := range scores {
+		byNode[score.NodePublicKey] = score
+	}
+
+	c.Equal("0.20000", byNode["node1"].MedianSuccessLatency)
+	c.InDelta(0.4, byNode["node1"].Metadata.P90, 0.0001)
+	c.Equal(5, byNode["node1"].Metadata.Attempts)
+	c.InDelta(0.8, byNode["node1"].Metadata.SuccessRate, 0.0001)
+	c.Equal("app", byNode["node1"].Metadata.ApplicationPublicKey)
+	c.Equal(100, byNode["node1"].SessionHeight)
+	c.Equal("0.05000", byNode["node2"].MedianSuccessLatency)
+
+	// Scores are only returned once per session
+	c.Empty(recorder.Scores("session-key"))
+
+	var nilRecorder *LatencyRecorder
+	nilRecorder.Record(session, "0021", node1, time.Second, true)
+	c.Nil(nilRecorder.Scores("session-key"))
+}
+
+func TestPercentile(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal(float64(0), percentile(nil, 50))
+	c.Equal(float64(1), percentile([]float64{1}, 90))
+	c.Equal(float64(2), percentile([]float64{1, 2, 3, 4}, 50))
+	c.Equal(float64(4), percentile([]float64{1, 2, 3, 4}, 90))
+}
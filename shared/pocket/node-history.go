@@ -0,0 +1,144 @@
+package pocket
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pokt-foundation/pocket-go/provider"
+)
+
+// Defaults of the node history policy
+const (
+	defaultNodeHistorySize           = 10
+	defaultNodeHistoryRequiredPasses = 3
+)
+
+// NodeCheckResult is the verdict of a check on a node of a session
+type NodeCheckResult struct {
+	NodePublicKey        string `json:"nodePublicKey"`
+	Check                string `json:"check"`
+	Chain                string `json:"chain"`
+	SessionKey           string `json:"sessionKey"`
+	ApplicationPublicKey string `json:"applicationPublicKey"`
+	Passed               bool   `json:"passed"`
+	// Value is the result obtained from the node, like its block height or chain id
+	Value     string    `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NodeHistoryKey returns the key the check history of a node is cached on
+func NodeHistoryKey(commitHash, chain, nodePublicKey string) string {
+	return fmt.Sprintf("%s{%s}-%s-check-history", commitHash, chain, nodePublicKey)
+}
+
+// NodeHistoryPolicy sets how many results are kept per node and how many consecutive
+// runs a node has to pass after failing before it's admitted again
+type NodeHistoryPolicy struct {
+	Size           int
+	RequiredPasses int
+}
+
+// withDefaults fills the empty fields of the policy with the defaults
+func (p NodeHistoryPolicy) withDefaults() NodeHistoryPolicy {
+	if p.Size <= 0 {
+		p.Size = defaultNodeHistorySize
+	}
+	if p.RequiredPasses <= 0 {
+		p.RequiredPasses = defaultNodeHistoryRequiredPasses
+	}
+	return p
+}
+
+// NodeHistory is the rolling history of the check results of a node, a node that fails a check
+// is penalized until it passes the checks on the amount of consecutive runs the policy requires
+type NodeHistory struct {
+	Results           []*NodeCheckResult `json:"results"`
+	ConsecutivePasses int                `json:"consecutivePasses"`
+	Penalized         bool               `json:"penalized"`
+	LastRunID         string             `json:"lastRunID"`
+}
+
+// Add appends the result of a check done on the given run to the history, a node is checked on
+// multiple sessions per run so only one pass is counted per run, and none if any check failed
+func (h *NodeHistory) Add(result *NodeCheckResult, runID string, policy NodeHistoryPolicy) {
+	policy = policy.withDefaults()
+
+	isNewRun := runID != h.LastRunID
+	h.LastRunID = runID
+
+	switch {
+	case !result.Passed:
+		h.Penalized = true
+		h.ConsecutivePasses = 0
+	case isNewRun:
+		h.ConsecutivePasses++
+	}
+
+	if h.Penalized && h.ConsecutivePasses >= policy.RequiredPasses {
+		h.Penalized = false
+	}
+
+	h.Results = append(h.Results, result)
+	if len(h.Results) > policy.Size {
+		h.Results = h.Results[len(h.Results)-policy.Size:]
+	}
+}
+
+// IsAdmitted returns whether the node can serve relays according to its history
+func (h *NodeHistory) IsAdmitted() bool {
+	return !h.Penalized
+}
+
+// NodeResultRecorder keeps the check results of the nodes of each session, safe for
+// concurrent use. A nil recorder doesn't record anything.
+type NodeResultRecorder struct {
+	mu       sync.Mutex
+	sessions map[string][]*NodeCheckResult
+}
+
+// NewNodeResultRecorder returns an empty node result recorder
+func NewNodeResultRecorder() *NodeResultRecorder {
+	return &NodeResultRecorder{
+		sessions: make(map[string][]*NodeCheckResult),
+	}
+}
+
+// Record saves the result of a check on a node of the session
+func (nr *NodeResultRecorder) Record(session *provider.Session, check, chain string, node *provider.Node, passed bool, value string) {
+	if nr == nil || session == nil || node == nil {
+		return
+	}
+
+	result := &NodeCheckResult{
+		NodePublicKey: node.PublicKey,
+		Check:         check,
+		Chain:         chain,
+		SessionKey:    session.Key,
+		Passed:        passed,
+		Value:         value,
+		Timestamp:     time.Now(),
+	}
+	if session.Header != nil {
+		result.ApplicationPublicKey = session.Header.AppPublicKey
+	}
+
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+	nr.sessions[session.Key] = append(nr.sessions[session.Key], result)
+}
+
+// Results returns the results recorded for the session, which are then discarded
+func (nr *NodeResultRecorder) Results(sessionKey string) []*NodeCheckResult {
+	if nr == nil {
+		return nil
+	}
+
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	results := nr.sessions[sessionKey]
+	delete(nr.sessions, sessionKey)
+
+	return results
+}
@@ -0,0 +1,63 @@
+package pocket
+
+import (
+	"testing"
+
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeHistory_Add(t *testing.T) {
+	c := require.New(t)
+
+	policy := NodeHistoryPolicy{Size: 3, RequiredPasses: 2}
+	pass := &NodeCheckResult{Passed: true}
+	fail := &NodeCheckResult{Passed: false}
+
+	history := &NodeHistory{}
+	history.Add(pass, "run1", policy)
+	c.True(history.IsAdmitted())
+
+	history.Add(fail, "run2", policy)
+	c.False(history.IsAdmitted())
+
+	// Passes on the run of the failure or on other sessions of the same run don't count
+	history.Add(pass, "run2", policy)
+	history.Add(pass, "run3", policy)
+	history.Add(pass, "run3", policy)
+	c.Equal(1, history.ConsecutivePasses)
+	c.False(history.IsAdmitted())
+
+	history.Add(pass, "run4", policy)
+	c.True(history.IsAdmitted())
+	c.Len(history.Results, 3)
+
+	// Flapping nodes have to pass again the required runs
+	history.Add(fail, "run5", policy)
+	history.Add(pass, "run6", policy)
+	c.False(history.IsAdmitted())
+}
+
+func TestNodeResultRecorder_Results(t *testing.T) {
+	c := require.New(t)
+
+	recorder := NewNodeResultRecorder()
+	session := &provider.Session{
+		Key:    "session-key",
+		Header: &provider.SessionHeader{AppPublicKey: "app"},
+	}
+
+	recorder.Record(session, SyncCheckName, "0021", &provider.Node{PublicKey: "node1"}, true, "100")
+	recorder.Record(session, ChainCheckName, "0021", &provider.Node{PublicKey: "node1"}, false, "2")
+
+	results := recorder.Results("session-key")
+	c.Len(results, 2)
+	c.Equal("app", results[0].ApplicationPublicKey)
+	c.Equal("100", results[0].Value)
+	c.False(results[1].Passed)
+	c.Empty(recorder.Results("session-key"))
+
+	var nilRecorder *NodeResultRecorder
+	nilRecorder.Record(session, SyncCheckName, "0021", &provider.Node{PublicKey: "node1"}, true, "100")
+	c.Nil(nilRecorder.Results("session-key"))
+}
@@ -0,0 +1,43 @@
+package pocket
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/pokt-foundation/pocket-go/provider"
+)
+
+const blocksPerSessionKey = "pos/BlocksPerSession"
+
+// ErrBlocksPerSessionNotFound when the network params don't include the blocks per session
+var ErrBlocksPerSessionNotFound = errors.New("blocks per session param not found")
+
+// GetBlocksPerSession returns the amount of blocks a session lasts on the network
+func GetBlocksPerSession(rpcProvider *provider.Provider) (int, error) {
+	params, err := rpcProvider.GetAllParams(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := params.PocketParams.Get(blocksPerSessionKey)
+	if !ok {
+		return 0, ErrBlocksPerSessionNotFound
+	}
+
+	return strconv.Atoi(value)
+}
+
+// GetSessionHeight returns the height of the session the given block belongs to,
+// sessions start on the first block after a multiple of the blocks per session
+func GetSessionHeight(blockHeight, blocksPerSession int) int {
+	if blocksPerSession <= 0 || blockHeight <= 0 {
+		return blockHeight
+	}
+
+	return blockHeight - (blockHeight-1)%blocksPerSession
+}
+
+// GetNextSessionHeight returns the height of the session following the one the given block belongs to
+func GetNextSessionHeight(blockHeight, blocksPerSession int) int {
+	return GetSessionHeight(blockHeight, blocksPerSession) + blocksPerSession
+}
@@ -0,0 +1,21 @@
+package pocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSessionHeight(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal(1, GetSessionHeight(1, 4))
+	c.Equal(1, GetSessionHeight(4, 4))
+	c.Equal(5, GetSessionHeight(5, 4))
+	c.Equal(5, GetSessionHeight(8, 4))
+	c.Equal(9, GetSessionHeight(9, 4))
+	c.Equal(10, GetSessionHeight(10, 0))
+
+	c.Equal(5, GetNextSessionHeight(1, 4))
+	c.Equal(9, GetNextSessionHeight(8, 4))
+}
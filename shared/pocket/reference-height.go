@@ -0,0 +1,128 @@
+package pocket
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	"github.com/Pocket/global-services/shared/metrics"
+	"github.com/Pocket/global-services/shared/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+type referenceBlockHeight struct {
+	URL         string
+	BlockHeight int64
+	Err         error
+}
+
+// referenceURLs returns the altruist and the additional reference endpoints of the
+// chain, without duplicates
+func (o *SyncCheckOptions) referenceURLs() []string {
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, url := range append([]string{o.AltruistURL}, o.ReferenceURLs...) {
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		urls = append(urls, url)
+	}
+
+	return urls
+}
+
+// getReferenceBlockHeight queries all the reference endpoints of the chain concurrently and
+// returns the median of the block heights obtained, 0 if none of them answered
+func (sc *SyncChecker) getReferenceBlockHeight(ctx context.Context, options *SyncCheckOptions) int64 {
+	urls := options.referenceURLs()
+	results := make([]*referenceBlockHeight, len(urls))
+
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			start := time.Now()
+
+			blockHeight, err := getAltruistBlockHeight(options, url)
+			results[i] = &referenceBlockHeight{URL: url, BlockHeight: blockHeight, Err: err}
+
+			sc.logReferenceHealth(ctx, results[i], time.Since(start), options)
+		}(i, url)
+	}
+	wg.Wait()
+
+	heights := []int64{}
+	for _, result := range results {
+		if result.Err == nil && result.BlockHeight > 0 {
+			heights = append(heights, result.BlockHeight)
+		}
+	}
+
+	return getMedianBlockHeight(heights)
+}
+
+// logReferenceHealth logs the status of a reference endpoint, failures are also
+// recorded as error metrics
+func (sc *SyncChecker) logReferenceHealth(ctx context.Context, result *referenceBlockHeight, elapsed time.Duration, options *SyncCheckOptions) {
+	fields := log.Fields{
+		"sessionKey":    options.Session.Key,
+		"blockchainID":  options.Blockchain,
+		"requestID":     sc.RequestID,
+		"serviceNode":   "ALTRUIST",
+		"serviceDomain": utils.GetDomainFromURL(result.URL),
+		"altruistURL":   redactAltruistURL(result.URL),
+		"blockHeight":   result.BlockHeight,
+		"elapsedTime":   elapsed.Seconds(),
+	}
+
+	errMsg := ""
+	switch {
+	case result.Err != nil:
+		errMsg = result.Err.Error()
+	case result.BlockHeight <= 0:
+		errMsg = "invalid block height"
+	}
+
+	if errMsg == "" {
+		logger.Log.WithFields(fields).Info("sync check: reference endpoint healthy")
+		return
+	}
+
+	fields["error"] = errMsg
+	logger.Log.WithFields(fields).Error("sync check: reference endpoint failure: " + errMsg)
+
+	if sc.MetricsRecorder == nil {
+		return
+	}
+
+	sc.MetricsRecorder.WriteErrorMetric(ctx, &metrics.Metric{
+		Timestamp:            time.Now(),
+		ApplicationPublicKey: options.Session.Header.AppPublicKey,
+		Blockchain:           options.Blockchain,
+		NodePublicKey:        "ALTRUIST " + utils.GetDomainFromURL(result.URL),
+		ElapsedTime:          elapsed.Seconds(),
+		Bytes:                len(errMsg),
+		Method:               "altruistcheck",
+		Message:              errMsg,
+		RequestID:            sc.RequestID,
+	})
+}
+
+// getMedianBlockHeight returns the median of the block heights, the even case
+// is rounded down to stay on a height that was actually reported
+func getMedianBlockHeight(heights []int64) int64 {
+	if len(heights) == 0 {
+		return 0
+	}
+
+	sorted := append([]int64{}, heights...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	return sorted[(len(sorted)-1)/2]
+}
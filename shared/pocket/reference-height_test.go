@@ -0,0 +1,58 @@
+package pocket
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-foundation/portal-db/types"
+	"github.com/pokt-foundation/utils-go/mock-client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncChecker_getReferenceBlockHeight(t *testing.T) {
+	c := require.New(t)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	mock.AddMockedResponse(http.MethodPost, "https://altruist1.com", http.StatusOK, `{"result":"0x64"}`)
+	mock.AddMockedResponse(http.MethodPost, "https://altruist2.com", http.StatusOK, `{"result":"0x65"}`)
+	mock.AddMockedResponse(http.MethodPost, "https://altruist3.com", http.StatusOK, `{"result":"0xffff"}`)
+	mock.AddMockedResponse(http.MethodPost, "https://altruist4.com", http.StatusOK, `{"error":"down"}`)
+
+	sc := &SyncChecker{}
+	options := &SyncCheckOptions{
+		Session: provider.Session{
+			Key:    "session-key",
+			Header: &provider.SessionHeader{AppPublicKey: "app"},
+		},
+		SyncCheckOptions: types.SyncCheckOptions{
+			Body:      `{"method":"eth_blockNumber"}`,
+			ResultKey: "result",
+		},
+		AltruistURL:   "https://altruist1.com",
+		ReferenceURLs: []string{"https://altruist1.com", "https://altruist2.com", "https://altruist3.com", "https://altruist4.com"},
+		Blockchain:    "0021",
+	}
+
+	c.Equal([]string{"https://altruist1.com", "https://altruist2.com", "https://altruist3.com", "https://altruist4.com"},
+		options.referenceURLs())
+
+	// A single endpoint reporting a height far ahead doesn't move the median
+	c.Equal(int64(101), sc.getReferenceBlockHeight(context.Background(), options))
+
+	options.AltruistURL, options.ReferenceURLs = "https://altruist4.com", nil
+	c.Equal(int64(0), sc.getReferenceBlockHeight(context.Background(), options))
+}
+
+func TestGetMedianBlockHeight(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal(int64(0), getMedianBlockHeight(nil))
+	c.Equal(int64(10), getMedianBlockHeight([]int64{10}))
+	c.Equal(int64(10), getMedianBlockHeight([]int64{12, 10}))
+	c.Equal(int64(11), getMedianBlockHeight([]int64{1000, 10, 11}))
+}
@@ -0,0 +1,132 @@
+package pocket
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/Pocket/global-services/shared/environment"
+	"github.com/Pocket/global-services/shared/utils"
+
+	logger "github.com/Pocket/global-services/shared/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// resultExtractorChains is a JSON object of the chains whose results are obtained
+	// with one of the named extractors, i.e {"0054": "tendermint"}
+	resultExtractorChains = environment.GetString("RESULT_EXTRACTOR_CHAINS", "")
+
+	resultExtractorsMu sync.RWMutex
+	resultExtractors   = map[string]utils.IntegerParser{}
+)
+
+// namedResultExtractors are the extractors of the non-EVM response shapes, chains are
+// assigned one of them through RESULT_EXTRACTOR_CHAINS
+var namedResultExtractors = map[string]utils.IntegerParser{
+	// CometBFT /status and /block responses and the cosmos sdk latest block, which moved
+	// from block to sdk_block between sdk versions
+	"tendermint": fallbackPathsExtractor(
+		"result.sync_info.latest_block_height",
+		"result.block.header.height",
+		"sdk_block.header.height",
+		"block.header.height",
+	),
+	// NEAR status and block responses
+	"near": fallbackPathsExtractor(
+		"result.sync_info.latest_block_height",
+		"result.header.height",
+	),
+}
+
+func init() {
+	if resultExtractorChains == "" {
+		return
+	}
+
+	chains := map[string]string{}
+	if err := json.Unmarshal([]byte(resultExtractorChains), &chains); err != nil {
+		logger.Log.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Error("error parsing result extractor chains: " + err.Error())
+		return
+	}
+
+	for blockchainID, name := range chains {
+		extractor, ok := namedResultExtractors[name]
+		if !ok {
+			logger.Log.WithFields(log.Fields{
+				"blockchainID": blockchainID,
+				"extractor":    name,
+			}).Error("invalid result extractor: " + name)
+			continue
+		}
+		RegisterResultExtractor(blockchainID, extractor)
+	}
+}
+
+// fallbackPathsExtractor returns an extractor that tries the key of the check and then each
+// of the paths given, for chains whose nodes report the same value on different response shapes
+func fallbackPathsExtractor(paths ...string) utils.IntegerParser {
+	return func(payload, key string) (int64, error) {
+		if key != "" {
+			if result, err := utils.ParseIntegerJSONString(payload, key); err == nil {
+				return result, nil
+			}
+		}
+
+		err := errors.New("no paths")
+		for _, path := range paths {
+			var result int64
+			if result, err = utils.ParseIntegerJSONString(payload, path); err == nil {
+				return result, nil
+			}
+		}
+
+		return 0, errors.New("no result found on the response: " + err.Error())
+	}
+}
+
+// RegisterResultExtractor sets how the integer results of the checks, like the block height
+// or the chain id, are obtained from the responses of the chain's nodes, for chains whose
+// responses can't be handled by the default JSON path parser. Meant to be called on init.
+func RegisterResultExtractor(blockchainID string, extractor utils.IntegerParser) {
+	resultExtractorsMu.Lock()
+	defer resultExtractorsMu.Unlock()
+
+	resultExtractors[blockchainID] = extractor
+}
+
+// getResultExtractor returns the result extractor of the chain, the JSON path parser by default
+func getResultExtractor(blockchainID string) utils.IntegerParser {
+	resultExtractorsMu.RLock()
+	defer resultExtractorsMu.RUnlock()
+
+	if extractor, ok := resultExtractors[blockchainID]; ok {
+		return extractor
+	}
+
+	return utils.ParseIntegerJSONString
+}
+
+// getChainIDExtractor returns the parser of the chain ids reported by the nodes of the chain,
+// chains with a result extractor registered have their integer results formatted as decimal
+func getChainIDExtractor(blockchainID string) utils.StringParser {
+	resultExtractorsMu.RLock()
+	extractor, ok := resultExtractors[blockchainID]
+	resultExtractorsMu.RUnlock()
+
+	if !ok {
+		return utils.ParseScalarJSONString
+	}
+
+	return func(payload, key string) (string, error) {
+		result, err := extractor(payload, key)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.FormatInt(result, 10), nil
+	}
+}
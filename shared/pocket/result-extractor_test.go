@@ -0,0 +1,46 @@
+package pocket
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetResultExtractor(t *testing.T) {
+	c := require.New(t)
+
+	RegisterResultExtractor("test-chain", func(payload, key string) (int64, error) {
+		return int64(len(strings.Split(payload, ","))), nil
+	})
+
+	height, err := getResultExtractor("test-chain")("a,b,c", "result")
+	c.NoError(err)
+	c.Equal(int64(3), height)
+
+	height, err = getResultExtractor("0021")(`{"result":"0x3"}`, "result")
+	c.NoError(err)
+	c.Equal(int64(3), height)
+}
+
+func TestNamedResultExtractors(t *testing.T) {
+	c := require.New(t)
+
+	tendermint := namedResultExtractors["tendermint"]
+
+	height, err := tendermint(`{"result":{"sync_info":{"latest_block_height":"12"}}}`, "")
+	c.NoError(err)
+	c.Equal(int64(12), height)
+
+	height, err = tendermint(`{"sdk_block":{"header":{"height":"13"}}}`, "")
+	c.NoError(err)
+	c.Equal(int64(13), height)
+
+	// The key of the check is tried first
+	height, err = tendermint(`{"height":"14","block":{"header":{"height":"13"}}}`, "height")
+	c.NoError(err)
+	c.Equal(int64(14), height)
+
+	_, err = tendermint(`{"result":{}}`, "")
+	c.EqualError(err, "no result found on the response: key block not found")
+}
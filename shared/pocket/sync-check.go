@@ -3,15 +3,18 @@ package pocket
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Pocket/global-services/shared/environment"
 	logger "github.com/Pocket/global-services/shared/logger"
 	"github.com/Pocket/global-services/shared/metrics"
 	"github.com/Pocket/global-services/shared/utils"
@@ -23,15 +26,49 @@ import (
 	_http "github.com/Pocket/global-services/shared/http"
 )
 
-var httpClient = _http.NewClient()
+var (
+	httpClient = _http.NewClient()
+
+	syncCheckKeyPrefix = environment.GetString("SYNC_CHECK_KEY_PREFIX", "sync-check-")
+	// blockHashCheckChains is a JSON object of the chains to check for block hash consistency
+	// and their options, an empty object uses the EVM defaults, i.e {"0021": {}}
+	blockHashCheckChains = environment.GetString("BLOCK_HASH_CHECK_CHAINS", "")
+	// syncCheckReferenceURLs is a JSON object of the chains with reference endpoints queried
+	// along with the altruist to obtain the block height, i.e {"0021": ["https://..."]}
+	syncCheckReferenceURLs = environment.GetString("SYNC_CHECK_REFERENCE_URLS", "")
+	// highestHeightChains is a JSON object of the chains with a custom method to obtain the
+	// highest block height of the nodes, i.e {"0021": {"method": "agreement", "minAgreement": 3}}
+	highestHeightChains = environment.GetString("SYNC_CHECK_HIGHEST_HEIGHT_CHAINS", "")
+	// altruistTrustChains is a JSON object of the chains with their own altruist trust
+	// options, i.e {"0021": {"threshold": 0.7, "minValidNodes": 4}}
+	altruistTrustChains = environment.GetString("ALTRUIST_TRUST_CHAINS", "")
+	// syncCheckMethods is a JSON object of the chains whose sync check is not sent
+	// with the default method, i.e {"0003": "GET"}
+	syncCheckMethods = environment.GetString("SYNC_CHECK_METHODS", "")
+)
+
+// SyncCheckName is the name of the sync check
+const SyncCheckName = "synccheck"
+
+func init() {
+	RegisterCheck(SyncCheckName, newSyncCheck)
+}
 
 // SyncChecker is the struct to perform sync checks on app sessions
 type SyncChecker struct {
 	Relayer                *relayer.Relayer
 	DefaultSyncAllowance   int
 	AltruistTrustThreshold float32
-	MetricsRecorder        *metrics.Recorder
-	RequestID              string
+	// AltruistMinValidNodes is the minimum amount of valid nodes needed to distrust the altruist
+	AltruistMinValidNodes int
+	MetricsRecorder       *metrics.Recorder
+	RequestID             string
+	// Challenger challenges the nodes out of sync with the majority, challenges are disabled when nil
+	Challenger *Challenger
+	// LatencyRecorder records the latency of the relays to the nodes, not recorded when nil
+	LatencyRecorder *LatencyRecorder
+	// ResultRecorder records the verdict on each node, not recorded when nil
+	ResultRecorder *NodeResultRecorder
 }
 
 // SyncCheckOptions is the struct of the data needed to perform a sync check
@@ -40,12 +77,41 @@ type SyncCheckOptions struct {
 	PocketAAT        provider.PocketAAT
 	SyncCheckOptions types.SyncCheckOptions
 	AltruistURL      string
-	Blockchain       string
+	// Method is the HTTP method of the sync check, POST when there's a body and GET otherwise
+	Method string
+	// ReferenceURLs are endpoints queried along with the altruist, the reference
+	// block height is the median of all their responses
+	ReferenceURLs []string
+	Blockchain    string
+	// AltruistTrust overrides the altruist trust options of the checker for the chain
+	AltruistTrust *AltruistTrustOptions
+	// HighestHeight sets how the highest block height of the nodes is obtained
+	HighestHeight HighestHeightOptions
+	// BlockHashCheck enables checking the nodes in sync agree on the hash of a common block
+	BlockHashCheck *BlockHashCheckOptions
+}
+
+// method returns the HTTP method of the sync check, the configured one or POST
+// when there's a body to send and GET otherwise
+func (o *SyncCheckOptions) method() string {
+	switch {
+	case o.Method != "":
+		return o.Method
+	case o.body() == "":
+		return http.MethodGet
+	}
+
+	return http.MethodPost
+}
+
+func (o *SyncCheckOptions) body() string {
+	return strings.Replace(o.SyncCheckOptions.Body, `\`, "", -1)
 }
 
 type nodeSyncLog struct {
 	Node        *provider.Node
 	BlockHeight int64
+	Relay       *relayer.Output
 }
 
 // Check performs a sync check of all the nodes of a given session
@@ -56,12 +122,13 @@ func (sc *SyncChecker) Check(ctx context.Context, options SyncCheckOptions) []st
 	allowance := int64(options.SyncCheckOptions.Allowance)
 
 	checkedNodes := []string{}
+	syncedNodeLogs := []*nodeSyncLog{}
 	nodeLogs := sc.getNodeSyncLogs(ctx, &options)
 	sort.Slice(nodeLogs, func(i, j int) bool {
 		return nodeLogs[i].BlockHeight > nodeLogs[j].BlockHeight
 	})
 
-	altruistBlockHeight, highestBlockHeight, isAltruistTrustworthy := sc.getAltruistDataAndHighestBlockHeight(nodeLogs, &options)
+	altruistBlockHeight, highestBlockHeight, isAltruistTrustworthy := sc.getAltruistDataAndHighestBlockHeight(ctx, nodeLogs, &options)
 
 	maxAllowedBlockHeight := int64(0)
 	if isAltruistTrustworthy {
@@ -96,6 +163,14 @@ func (sc *SyncChecker) Check(ctx context.Context, options SyncCheckOptions) []st
 			continue
 		}
 
+		syncedNodeLogs = append(syncedNodeLogs, node)
+	}
+
+	if options.BlockHashCheck != nil {
+		syncedNodeLogs = sc.checkBlockHashes(ctx, syncedNodeLogs, &options)
+	}
+
+	for _, node := range syncedNodeLogs {
 		logger.Log.WithFields(log.Fields{
 			"sessionKey":            options.Session.Key,
 			"blockchainID":          options.Blockchain,
@@ -105,9 +180,9 @@ func (sc *SyncChecker) Check(ctx context.Context, options SyncCheckOptions) []st
 			"serviceNode":           node.Node.PublicKey,
 			"allowance":             options.SyncCheckOptions.Allowance,
 			"appplicationPublicKey": options.Session.Header.AppPublicKey,
-		}).Info(fmt.Sprintf("SYNC CHECK IN-SYNC: %s height: %d", publicKey, blockHeight))
+		}).Info(fmt.Sprintf("SYNC CHECK IN-SYNC: %s height: %d", node.Node.PublicKey, node.BlockHeight))
 
-		checkedNodes = append(checkedNodes, publicKey)
+		checkedNodes = append(checkedNodes, node.Node.PublicKey)
 	}
 
 	logger.Log.WithFields(log.Fields{
@@ -117,11 +192,66 @@ func (sc *SyncChecker) Check(ctx context.Context, options SyncCheckOptions) []st
 		"appplicationPublicKey": options.Session.Header.AppPublicKey,
 	}).Info(fmt.Sprintf("SYNC CHECK COMPLETE: %d nodes in sync", len(checkedNodes)))
 
-	// TODO: Implement challenge
+	if sc.Challenger != nil && len(checkedNodes) < len(nodeLogs) {
+		sc.challenge(ctx, nodeLogs, syncedNodeLogs, &options)
+	}
+
+	sc.recordResults(nodeLogs, checkedNodes, &options)
 
 	return checkedNodes
 }
 
+// recordResults records the verdict and block height of every node of the session
+func (sc *SyncChecker) recordResults(nodeLogs []*nodeSyncLog, checkedNodes []string, options *SyncCheckOptions) {
+	if sc.ResultRecorder == nil {
+		return
+	}
+
+	passed := make(map[string]bool, len(checkedNodes))
+	for _, publicKey := range checkedNodes {
+		passed[publicKey] = true
+	}
+
+	for _, node := range nodeLogs {
+		sc.ResultRecorder.Record(&options.Session, SyncCheckName, options.Blockchain, node.Node,
+			passed[node.Node.PublicKey], strconv.FormatInt(node.BlockHeight, 10))
+	}
+}
+
+// challenge submits a challenge against the nodes that failed the check, with the
+// block heights of the synced nodes as evidence
+func (sc *SyncChecker) challenge(ctx context.Context, nodeLogs, syncedNodeLogs []*nodeSyncLog, options *SyncCheckOptions) {
+	synced := make(map[string]bool, len(syncedNodeLogs))
+	majority := make([]*NodeResponse, 0, len(syncedNodeLogs))
+	for _, node := range syncedNodeLogs {
+		synced[node.Node.PublicKey] = true
+		majority = append(majority, newSyncNodeResponse(node))
+	}
+
+	minority := []*NodeResponse{}
+	for _, node := range nodeLogs {
+		if !synced[node.Node.PublicKey] {
+			minority = append(minority, newSyncNodeResponse(node))
+		}
+	}
+
+	sc.Challenger.Challenge(ctx, ChallengeOptions{
+		Session:    options.Session,
+		Blockchain: options.Blockchain,
+		Check:      SyncCheckName,
+		Majority:   majority,
+		Minority:   minority,
+	})
+}
+
+func newSyncNodeResponse(node *nodeSyncLog) *NodeResponse {
+	return &NodeResponse{
+		Node:   node.Node,
+		Output: node.Relay,
+		Value:  strconv.FormatInt(node.BlockHeight, 10),
+	}
+}
+
 func (sc *SyncChecker) getNodeSyncLogs(ctx context.Context, options *SyncCheckOptions) []*nodeSyncLog {
 	nodeLogsChan := make(chan *nodeSyncLog, len(options.Session.Nodes))
 	nodeLogs := []*nodeSyncLog{}
@@ -148,15 +278,16 @@ func (sc *SyncChecker) getNodeSyncLogs(ctx context.Context, options *SyncCheckOp
 func (sc *SyncChecker) getNodeSyncLog(ctx context.Context, node *provider.Node, nodeLogs chan<- *nodeSyncLog, options *SyncCheckOptions) {
 	start := time.Now()
 
-	blockHeight, err := utils.GetIntFromRelay(*sc.Relayer, relayer.Input{
+	blockHeight, relay, err := utils.GetParsedIntFromRelay(*sc.Relayer, relayer.Input{
 		Blockchain: options.Blockchain,
-		Data:       strings.Replace(options.SyncCheckOptions.Body, `\`, "", -1),
-		Method:     http.MethodPost,
+		Data:       options.body(),
+		Method:     options.method(),
 		PocketAAT:  &options.PocketAAT,
 		Session:    &options.Session,
 		Node:       node,
 		Path:       options.SyncCheckOptions.Path,
-	}, options.SyncCheckOptions.ResultKey)
+	}, options.SyncCheckOptions.ResultKey, getResultExtractor(options.Blockchain))
+	sc.LatencyRecorder.Record(&options.Session, options.Blockchain, node, time.Since(start), err == nil)
 
 	if err != nil {
 		logger.Log.WithFields(log.Fields{
@@ -183,6 +314,7 @@ func (sc *SyncChecker) getNodeSyncLog(ctx context.Context, node *provider.Node,
 		nodeLogs <- &nodeSyncLog{
 			Node:        node,
 			BlockHeight: 0,
+			Relay:       relay,
 		}
 		return
 	}
@@ -190,20 +322,17 @@ func (sc *SyncChecker) getNodeSyncLog(ctx context.Context, node *provider.Node,
 	nodeLogs <- &nodeSyncLog{
 		Node:        node,
 		BlockHeight: blockHeight,
+		Relay:       relay,
 	}
 }
 
-func (sc *SyncChecker) getAltruistDataAndHighestBlockHeight(nodeLogs []*nodeSyncLog, options *SyncCheckOptions) (altruistBlockHeight, highestBlockHeight int64, isAltruistTrustworthy bool) {
+func (sc *SyncChecker) getAltruistDataAndHighestBlockHeight(ctx context.Context, nodeLogs []*nodeSyncLog, options *SyncCheckOptions) (altruistBlockHeight, highestBlockHeight int64, isAltruistTrustworthy bool) {
 	validNodes, highestBlockHeight := sc.getValidNodesCountAndHighestNode(nodeLogs, options)
-	altruistBlockHeight, nodesAheadOfAltruist := sc.getValidatedAltruist(nodeLogs, options)
+	altruistBlockHeight, nodesAheadOfAltruist := sc.getValidatedAltruist(ctx, nodeLogs, options)
 
-	// Prevents division by 0 in case all nodes fail
-	divisionValidNodes := validNodes
-	if divisionValidNodes <= 0 {
-		divisionValidNodes = 1
-	}
-
-	isAltruistTrustworthy = float32(nodesAheadOfAltruist/divisionValidNodes) < sc.AltruistTrustThreshold
+	decision := evaluateAltruistTrust(altruistBlockHeight, highestBlockHeight, nodesAheadOfAltruist, validNodes, sc.altruistTrustOptions(options))
+	sc.recordAltruistTrust(ctx, decision, options)
+	isAltruistTrustworthy = decision.Trusted
 
 	if !isAltruistTrustworthy {
 		logger.Log.WithFields(log.Fields{
@@ -247,8 +376,25 @@ func (sc *SyncChecker) getValidNodesCountAndHighestNode(nodeLogs []*nodeSyncLog,
 		}).Error(errMsg)
 	}
 
+	heights := make([]int64, 0, len(nodeLogs))
+	for _, node := range nodeLogs {
+		heights = append(heights, node.BlockHeight)
+	}
+
+	// Without consensus the highest block height is left unset, so only a trusted altruist
+	// can be used as reference and no node is considered synced otherwise
+	highestBlockHeight, err := getHighestBlockHeight(heights, int64(options.SyncCheckOptions.Allowance), options.HighestHeight)
+	if err != nil {
+		logger.Log.WithFields(log.Fields{
+			"sessionKey":   options.Session.Key,
+			"blockchainID": options.Blockchain,
+			"requestID":    sc.RequestID,
+			"error":        err.Error(),
+		}).Error("sync check: " + err.Error())
+		return validNodes, 0
+	}
+
 	errMsg = "sync check: top synced node result is invalid"
-	highestBlockHeight = nodeLogs[0].BlockHeight
 	if highestBlockHeight <= 0 {
 		logger.Log.WithFields(log.Fields{
 			"sessionKey":   options.Session.Key,
@@ -261,17 +407,17 @@ func (sc *SyncChecker) getValidNodesCountAndHighestNode(nodeLogs []*nodeSyncLog,
 	return validNodes, highestBlockHeight
 }
 
-// getValidatedAltruist obtains and validates altruist block height and also returns,
-// how many nodes are ahead of it
-func (sc *SyncChecker) getValidatedAltruist(nodeLogs []*nodeSyncLog, options *SyncCheckOptions) (int64, int) {
-	altruistBlockHeight, err := getAltruistBlockHeight(options.SyncCheckOptions, options.AltruistURL, options.SyncCheckOptions.Path)
-	if altruistBlockHeight == 0 || err != nil {
+// getValidatedAltruist obtains and validates the reference block height from the altruists
+// and also returns, how many nodes are ahead of it
+func (sc *SyncChecker) getValidatedAltruist(ctx context.Context, nodeLogs []*nodeSyncLog, options *SyncCheckOptions) (int64, int) {
+	altruistBlockHeight := sc.getReferenceBlockHeight(ctx, options)
+	if altruistBlockHeight == 0 {
 		logger.Log.WithFields(log.Fields{
 			"sessionKey":   options.Session.Key,
 			"blockchainID": options.Blockchain,
 			"requestID":    sc.RequestID,
 			"serviceNode":  "ALTRUIST",
-		}).Error("sync check: altruist failure: ", err)
+		}).Error("sync check: altruist failure: no reference endpoint returned a valid block height")
 	}
 	logger.Log.WithFields(log.Fields{
 		"sessionKey":   options.Session.Key,
@@ -294,19 +440,21 @@ func (sc *SyncChecker) getValidatedAltruist(nodeLogs []*nodeSyncLog, options *Sy
 	return altruistBlockHeight, nodesAheadOfAltruist
 }
 
-func getAltruistBlockHeight(options types.SyncCheckOptions, altruistURL string, path string) (int64, error) {
-	regex := regexp.MustCompile(`/[\w]*:\/\/[^\/]*@/g`)
-	regex.ReplaceAllString(altruistURL, "")
-
-	req, err := http.NewRequest(http.MethodPost, altruistURL+path,
-		bytes.NewBuffer([]byte(strings.Replace(options.Body, `\`, "", -1))))
+func getAltruistBlockHeight(options *SyncCheckOptions, altruistURL string) (int64, error) {
+	var body io.Reader
+	if data := options.body(); data != "" {
+		body = bytes.NewBufferString(data)
+	}
 
+	req, err := http.NewRequest(options.method(), altruistURL+options.SyncCheckOptions.Path, body)
 	if err != nil {
 		return 0, errors.New("error making altruist request: " + err.Error())
 	}
 	defer utils.CloseOrLog(req.Response)
 
-	req.Header.Add("Content-Type", "application/json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
 
 	res, err := httpClient.Do(req)
 	defer utils.CloseOrLog(res)
@@ -314,5 +462,109 @@ func getAltruistBlockHeight(options types.SyncCheckOptions, altruistURL string,
 		return 0, errors.New("error performing altruist request: " + err.Error())
 	}
 
-	return utils.ParseIntegerFromPayload(res.Body, options.ResultKey)
+	response, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, errors.New("error reading altruist response: " + err.Error())
+	}
+
+	return getResultExtractor(options.Blockchain)(string(response), options.SyncCheckOptions.ResultKey)
+}
+
+// syncCheck is the Check implementation of the sync checker
+type syncCheck struct {
+	checker         *SyncChecker
+	deps            *CheckDependencies
+	blockHashChecks map[string]*BlockHashCheckOptions
+	referenceURLs   map[string][]string
+	highestHeights  map[string]HighestHeightOptions
+	altruistTrusts  map[string]*AltruistTrustOptions
+	methods         map[string]string
+}
+
+func newSyncCheck(deps *CheckDependencies) (Check, error) {
+	blockHashChecks := map[string]*BlockHashCheckOptions{}
+	if blockHashCheckChains != "" {
+		if err := json.Unmarshal([]byte(blockHashCheckChains), &blockHashChecks); err != nil {
+			return nil, errors.New("error parsing block hash check chains: " + err.Error())
+		}
+	}
+
+	referenceURLs := map[string][]string{}
+	if syncCheckReferenceURLs != "" {
+		if err := json.Unmarshal([]byte(syncCheckReferenceURLs), &referenceURLs); err != nil {
+			return nil, errors.New("error parsing sync check reference urls: " + err.Error())
+		}
+	}
+
+	highestHeights := map[string]HighestHeightOptions{}
+	if highestHeightChains != "" {
+		if err := json.Unmarshal([]byte(highestHeightChains), &highestHeights); err != nil {
+			return nil, errors.New("error parsing highest height chains: " + err.Error())
+		}
+	}
+
+	altruistTrusts := map[string]*AltruistTrustOptions{}
+	if altruistTrustChains != "" {
+		if err := json.Unmarshal([]byte(altruistTrustChains), &altruistTrusts); err != nil {
+			return nil, errors.New("error parsing altruist trust chains: " + err.Error())
+		}
+	}
+
+	methods := map[string]string{}
+	if syncCheckMethods != "" {
+		if err := json.Unmarshal([]byte(syncCheckMethods), &methods); err != nil {
+			return nil, errors.New("error parsing sync check methods: " + err.Error())
+		}
+	}
+
+	return &syncCheck{
+		checker: &SyncChecker{
+			Relayer:                deps.Relayer,
+			DefaultSyncAllowance:   deps.DefaultSyncAllowance,
+			AltruistTrustThreshold: deps.AltruistTrustThreshold,
+			AltruistMinValidNodes:  deps.AltruistMinValidNodes,
+			MetricsRecorder:        deps.MetricsRecorder,
+			RequestID:              deps.RequestID,
+			Challenger:             deps.Challenger,
+			LatencyRecorder:        deps.LatencyRecorder,
+			ResultRecorder:         deps.ResultRecorder,
+		},
+		deps:            deps,
+		blockHashChecks: blockHashChecks,
+		referenceURLs:   referenceURLs,
+		highestHeights:  highestHeights,
+		altruistTrusts:  altruistTrusts,
+		methods:         methods,
+	}, nil
+}
+
+func (s *syncCheck) Name() string {
+	return SyncCheckName
+}
+
+func (s *syncCheck) IsApplicable(blockchain *types.Blockchain) bool {
+	return !(blockchain.SyncCheckOptions.Body == "" && blockchain.SyncCheckOptions.Path == "")
+}
+
+func (s *syncCheck) Run(ctx context.Context, options *CheckOptions) []string {
+	return s.checker.Check(ctx, SyncCheckOptions{
+		Session:          options.Session,
+		PocketAAT:        options.PocketAAT,
+		SyncCheckOptions: options.Blockchain.SyncCheckOptions,
+		AltruistURL:      options.Blockchain.Altruist,
+		Method:           s.methods[options.Blockchain.ID],
+		ReferenceURLs:    s.referenceURLs[options.Blockchain.ID],
+		AltruistTrust:    s.altruistTrusts[options.Blockchain.ID],
+		HighestHeight:    s.highestHeights[options.Blockchain.ID],
+		Blockchain:       options.Blockchain.ID,
+		BlockHashCheck:   s.blockHashChecks[options.Blockchain.ID],
+	})
+}
+
+func (s *syncCheck) CachePolicy(sessionKey string) CachePolicy {
+	policy := newCachePolicy(s.deps, syncCheckKeyPrefix, sessionKey)
+	// Nodes in sync are no longer considered failing
+	policy.EraseFailureMarks = true
+	policy.StickyPenalties = true
+	return policy
 }
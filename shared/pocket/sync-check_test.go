@@ -0,0 +1,44 @@
+package pocket
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/pokt-foundation/portal-db/types"
+	"github.com/pokt-foundation/utils-go/mock-client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncCheckOptions_method(t *testing.T) {
+	c := require.New(t)
+
+	options := &SyncCheckOptions{SyncCheckOptions: types.SyncCheckOptions{Body: `{"method":"eth_blockNumber"}`}}
+	c.Equal(http.MethodPost, options.method())
+
+	options = &SyncCheckOptions{SyncCheckOptions: types.SyncCheckOptions{Path: "/v2/status"}}
+	c.Equal(http.MethodGet, options.method())
+
+	options.Method = http.MethodPost
+	c.Equal(http.MethodPost, options.method())
+}
+
+func TestGetAltruistBlockHeight_Get(t *testing.T) {
+	c := require.New(t)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	mock.AddMockedResponse(http.MethodGet, "https://altruist.com/blocks/latest", http.StatusOK,
+		`{"block":{"header":{"chain_id":"osmosis-1","height":"7000000"}}}`)
+
+	height, err := getAltruistBlockHeight(&SyncCheckOptions{
+		SyncCheckOptions: types.SyncCheckOptions{
+			Path:      "/blocks/latest",
+			ResultKey: "block.header.height",
+		},
+		Blockchain: "0003",
+	}, "https://altruist.com")
+	c.NoError(err)
+	c.Equal(int64(7000000), height)
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBatcherClosed when an item is added to a closed batcher
+var ErrBatcherClosed = errors.New("batcher is closed")
+
+// BatcherOptions is the config of a batcher
+type BatcherOptions struct {
+	// MaxSize is the amount of items that triggers a flush
+	MaxSize int
+	// MaxWait is the longest an item waits on a partial batch before it's flushed, no limit when zero
+	MaxWait time.Duration
+	// MaxConcurrentFlushes is the amount of flushes allowed to run at the same time,
+	// batches are flushed one after the other when lower than 2
+	MaxConcurrentFlushes int
+}
+
+// Batcher groups the items added to it and flushes them once the batch reaches its max
+// size or its oldest item waited the max wait. Every item added is flushed exactly once,
+// even when the context is cancelled, in which case the pending items are flushed and
+// further items are rejected.
+type Batcher[T any] struct {
+	options BatcherOptions
+	flush   func(ctx context.Context, items []T)
+
+	mu      sync.RWMutex
+	closed  bool
+	items   chan T
+	stopped chan struct{}
+	done    chan struct{}
+	sem     chan struct{}
+	flushes sync.WaitGroup
+}
+
+// NewBatcher returns a running batcher that flushes the batches with the given function
+func NewBatcher[T any](ctx context.Context, options BatcherOptions, flush func(ctx context.Context, items []T)) *Batcher[T] {
+	if options.MaxSize <= 0 {
+		options.MaxSize = 1
+	}
+
+	b := &Batcher[T]{
+		options: options,
+		flush:   flush,
+		items:   make(chan T, options.MaxSize),
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if options.MaxConcurrentFlushes > 1 {
+		b.sem = make(chan struct{}, options.MaxConcurrentFlushes)
+	}
+
+	go b.run(ctx)
+
+	return b
+}
+
+// Add queues an item on the current batch, blocks while the batcher is at capacity
+func (b *Batcher[T]) Add(item T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrBatcherClosed
+	}
+
+	select {
+	case b.items <- item:
+		return nil
+	case <-b.stopped:
+		return ErrBatcherClosed
+	}
+}
+
+// Close flushes the pending items and waits for all the flushes to finish
+func (b *Batcher[T]) Close() {
+	b.stopAccepting()
+	<-b.done
+}
+
+// Done is closed once the batcher stopped and all its flushes finished
+func (b *Batcher[T]) Done() <-chan struct{} {
+	return b.done
+}
+
+func (b *Batcher[T]) stopAccepting() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.items)
+	}
+}
+
+func (b *Batcher[T]) run(ctx context.Context) {
+	defer close(b.done)
+	defer b.flushes.Wait()
+
+	pending := make([]T, 0, b.options.MaxSize)
+	var timer *time.Timer
+	var wait <-chan time.Time
+
+	flushPending := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, wait = nil, nil
+		}
+		if len(pending) == 0 {
+			return
+		}
+
+		b.dispatch(ctx, pending)
+		pending = make([]T, 0, b.options.MaxSize)
+	}
+
+	for {
+		select {
+		case item, ok := <-b.items:
+			if !ok {
+				flushPending()
+				return
+			}
+
+			pending = append(pending, item)
+			if len(pending) >= b.options.MaxSize {
+				flushPending()
+				continue
+			}
+
+			if timer == nil && b.options.MaxWait > 0 {
+				timer = time.NewTimer(b.options.MaxWait)
+				wait = timer.C
+			}
+		case <-wait:
+			timer, wait = nil, nil
+			flushPending()
+		case <-ctx.Done():
+			// Release the blocked adds before closing so no item is left behind
+			close(b.stopped)
+			b.stopAccepting()
+
+			for item := range b.items {
+				pending = append(pending, item)
+				if len(pending) >= b.options.MaxSize {
+					flushPending()
+				}
+			}
+			flushPending()
+			return
+		}
+	}
+}
+
+func (b *Batcher[T]) dispatch(ctx context.Context, items []T) {
+	if b.sem == nil {
+		b.flush(ctx, items)
+		return
+	}
+
+	b.sem <- struct{}{}
+	b.flushes.Add(1)
+	go func() {
+		defer b.flushes.Done()
+		defer func() { <-b.sem }()
+		b.flush(ctx, items)
+	}()
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testFlushes struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (f *testFlushes) flush(ctx context.Context, items []int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, items)
+}
+
+func (f *testFlushes) sizes() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sizes := []int{}
+	for _, batch := range f.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func TestBatcher_MaxSize(t *testing.T) {
+	c := require.New(t)
+
+	flushes := &testFlushes{}
+	batcher := NewBatcher(context.Background(), BatcherOptions{MaxSize: 2}, flushes.flush)
+	for i := 0; i < 5; i++ {
+		c.NoError(batcher.Add(i))
+	}
+	batcher.Close()
+
+	c.Equal([]int{2, 2, 1}, flushes.sizes())
+	c.Equal([]int{4}, flushes.batches[2])
+	c.ErrorIs(batcher.Add(6), ErrBatcherClosed)
+}
+
+func TestBatcher_MaxWait(t *testing.T) {
+	c := require.New(t)
+
+	flushes := &testFlushes{}
+	batcher := NewBatcher(context.Background(), BatcherOptions{
+		MaxSize: 10,
+		MaxWait: 10 * time.Millisecond,
+	}, flushes.flush)
+	defer batcher.Close()
+
+	c.NoError(batcher.Add(1))
+	c.Eventually(func() bool {
+		return len(flushes.sizes()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestBatcher_ContextCancelled(t *testing.T) {
+	c := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	flushes := &testFlushes{}
+	batcher := NewBatcher(ctx, BatcherOptions{MaxSize: 10, MaxConcurrentFlushes: 2}, flushes.flush)
+
+	c.NoError(batcher.Add(1))
+	c.NoError(batcher.Add(2))
+	cancel()
+
+	<-batcher.Done()
+	c.Equal([]int{2}, flushes.sizes())
+	c.ErrorIs(batcher.Add(3), ErrBatcherClosed)
+	batcher.Close()
+}
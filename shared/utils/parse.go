@@ -4,101 +4,326 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"math"
+	"math/big"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-// ParseIntegerFromPayload parses a string (or integer if any) value as an int from a JSON payload
-// Keys must be given in the form: "key1.nestedKey1.nestedKey2"
-// Should this be refactored to also support strings?
-func ParseIntegerFromPayload(r io.Reader, key string) (int64, error) {
-	// TODO: Parse nested fields
-	res := map[string]any{} // got'em 'any' haters
+// JSONPathMatches are all the values matched by a path containing wildcards
+type JSONPathMatches []any
 
-	if err := json.NewDecoder(r).Decode(&res); err != nil {
-		return 0, errors.New("error decoding payload: " + err.Error())
+type jsonPathToken struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// DecodeJSONPayload decodes a JSON payload keeping numbers as json.Number so big integers
+// don't lose precision. When the path is empty, payloads that aren't JSON are returned as plain text.
+func DecodeJSONPayload(payload, path string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	decoder.UseNumber()
+
+	var document any
+	err := decoder.Decode(&document)
+	if err == nil && !decoder.More() {
+		return document, nil
+	}
+
+	if path == "" {
+		return strings.TrimSpace(payload), nil
 	}
+	if err == nil {
+		err = errors.New("unexpected data after the JSON value")
+	}
+
+	return nil, errors.New("error decoding payload: " + err.Error())
+}
 
-	value, err := NestedMapLookup(res, key)
+// LookupJSONPath returns the value on the path of a decoded JSON document. Paths are given in
+// the form "key1.nestedKey1[0].nestedKey2", indices can be negative to count from the end and
+// "*" or "[*]" match every value of an object or array, i.e "[*].result" on batch responses.
+// Paths with wildcards return all the values found as JSONPathMatches, an empty path returns
+// the whole document.
+func LookupJSONPath(document any, path string) (any, error) {
+	tokens, err := parseJSONPath(path)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	values := []any{document}
+	hasWildcard := false
+	for _, token := range tokens {
+		hasWildcard = hasWildcard || token.wildcard
+
+		next := []any{}
+		for _, value := range values {
+			found, err := lookupJSONPathToken(value, token)
+			if err != nil {
+				// A wildcard can match values of different shapes, only the matching ones are kept
+				if hasWildcard {
+					continue
+				}
+				return nil, err
+			}
+			next = append(next, found...)
+		}
+		values = next
+	}
+
+	if !hasWildcard {
+		return values[0], nil
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values found on path %s", path)
+	}
+
+	return JSONPathMatches(values), nil
+}
+
+func lookupJSONPathToken(value any, token jsonPathToken) ([]any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if token.wildcard {
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			values := make([]any, 0, len(keys))
+			for _, key := range keys {
+				values = append(values, v[key])
+			}
+			return values, nil
+		}
+		if token.isIndex {
+			return nil, fmt.Errorf("index %d used on an object", token.index)
+		}
+
+		found, ok := v[token.key]
+		if !ok {
+			return nil, fmt.Errorf("key %s not found", token.key)
+		}
+		return []any{found}, nil
+	case []any:
+		if token.wildcard {
+			return v, nil
+		}
+		if !token.isIndex {
+			return nil, fmt.Errorf("key %s used on an array", token.key)
+		}
+
+		index := token.index
+		if index < 0 {
+			index += len(v)
+		}
+		if index < 0 || index >= len(v) {
+			return nil, fmt.Errorf("index %d out of range", token.index)
+		}
+		return []any{v[index]}, nil
+	}
+
+	if token.isIndex || token.wildcard {
+		return nil, fmt.Errorf("nested value is not an array or object: %T", value)
+	}
+	return nil, fmt.Errorf("nested key %s is not of an object", token.key)
+}
+
+func parseJSONPath(path string) ([]jsonPathToken, error) {
+	tokens := []jsonPathToken{}
+
+	addKey := func(key string) {
+		switch key {
+		case "":
+		case "*":
+			tokens = append(tokens, jsonPathToken{wildcard: true})
+		default:
+			tokens = append(tokens, jsonPathToken{key: key})
+		}
 	}
 
-	var valueDecimal int64
+	key := strings.Builder{}
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '.':
+			addKey(key.String())
+			key.Reset()
+		case '[':
+			addKey(key.String())
+			key.Reset()
+
+			end := strings.IndexByte(path[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid path %s: unclosed bracket", path)
+			}
+
+			index := path[i+1 : i+end]
+			if index == "*" {
+				tokens = append(tokens, jsonPathToken{wildcard: true})
+			} else {
+				n, err := strconv.Atoi(index)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %s: invalid index %s", path, index)
+				}
+				tokens = append(tokens, jsonPathToken{index: n, isIndex: true})
+			}
+			i += end
+		default:
+			key.WriteByte(path[i])
+		}
+	}
+	addKey(key.String())
+
+	return tokens, nil
+}
+
+// maxIntegerDigits caps the digits of the integers parsed, enough for any 256 bits integer
+const maxIntegerDigits = 78
+
+// ParseBigInteger parses a JSON number or a hex ("0x" prefixed) or decimal string as an integer,
+// from multiple matches the highest integer is returned. Numbers with fractions or exponents are
+// rejected instead of truncated.
+func ParseBigInteger(value any) (*big.Int, error) {
 	switch v := value.(type) {
+	case json.Number:
+		return parseBigIntegerString(v.String())
 	case string:
-		valueDecimal, err = strconv.ParseInt(v, 0, 64)
-	case int64:
-		valueDecimal = int64(v)
+		return parseBigIntegerString(v)
 	case float64:
-		valueDecimal = int64(v)
-	default:
-		err = fmt.Errorf("invalid type for payload: %T", v)
+		if math.IsInf(v, 0) || math.IsNaN(v) || v != math.Trunc(v) {
+			return nil, errors.New("invalid integer")
+		}
+		integer, _ := big.NewFloat(v).Int(nil)
+		return integer, nil
+	case int64:
+		return big.NewInt(v), nil
+	case JSONPathMatches:
+		var highest *big.Int
+		for _, match := range v {
+			integer, err := ParseBigInteger(match)
+			if err != nil {
+				continue
+			}
+			if highest == nil || integer.Cmp(highest) > 0 {
+				highest = integer
+			}
+		}
+		if highest == nil {
+			return nil, errors.New("no integer values found")
+		}
+		return highest, nil
 	}
 
-	if err != nil {
-		return 0, fmt.Errorf("error parsing field %s: %s", key, err.Error())
+	return nil, fmt.Errorf("invalid type for payload: %T", value)
+}
+
+// parseBigIntegerString only accepts hex and decimal integer literals, the values come from
+// nodes so they are never included on the errors
+func parseBigIntegerString(str string) (*big.Int, error) {
+	str = strings.Trim(strings.TrimSpace(str), `"`)
+
+	base := 10
+	if strings.HasPrefix(str, "0x") || strings.HasPrefix(str, "0X") {
+		str, base = str[2:], 16
 	}
 
-	return valueDecimal, nil
-}
+	if len(str) > maxIntegerDigits {
+		return nil, fmt.Errorf("integer exceeds %d digits", maxIntegerDigits)
+	}
 
-// ParseIntegerJSONString parses a string (or integer if any) value as an int from a JSON string.
-// Keys must be given in the form: "key1.nestedKey1.nestedKey2"
-// Should this be refactored to also support strings?
-func ParseIntegerJSONString(r string, key string) (int64, error) {
-	// TODO: Parse nested fields
-	res := map[string]any{} // got'em 'any' haters
+	integer, ok := new(big.Int).SetString(str, base)
+	if !ok {
+		if base == 16 {
+			return nil, errors.New("invalid hex integer")
+		}
+		return nil, errors.New("invalid integer")
+	}
 
-	err := json.Unmarshal([]byte(r), &res)
+	return integer, nil
+}
+
+// ParseBigIntegerJSONString parses the value on the path of a JSON string as a big integer,
+// see LookupJSONPath for the paths supported. An empty path parses the whole payload.
+func ParseBigIntegerJSONString(r string, key string) (*big.Int, error) {
+	document, err := DecodeJSONPayload(r, key)
 	if err != nil {
-		return 0, errors.New("error decoding payload: " + err.Error())
+		return nil, err
 	}
 
-	value, err := NestedMapLookup(res, key)
+	value, err := LookupJSONPath(document, key)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	var valueDecimal int64
-	switch v := value.(type) {
-	case string:
-		valueDecimal, err = strconv.ParseInt(v, 0, 64)
-	case int64:
-		valueDecimal = int64(v)
-	case float64:
-		valueDecimal = int64(v)
-	default:
-		err = fmt.Errorf("invalid type for payload: %T", v)
+	integer, err := ParseBigInteger(value)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing field %s: %s", key, err.Error())
 	}
 
+	return integer, nil
+}
+
+// ParseIntegerJSONString parses the value on the path of a JSON string as an int,
+// see LookupJSONPath for the paths supported. An empty path parses the whole payload.
+func ParseIntegerJSONString(r string, key string) (int64, error) {
+	integer, err := ParseBigIntegerJSONString(r, key)
 	if err != nil {
-		return 0, fmt.Errorf("error parsing field %s: %s", key, err.Error())
+		return 0, err
 	}
 
-	return valueDecimal, nil
+	if !integer.IsInt64() {
+		return 0, fmt.Errorf("error parsing field %s: integer overflows int64", key)
+	}
+
+	return integer.Int64(), nil
 }
 
-// NestedMapLookup returns the value of a map with an arbitrary number of nested
-// fields. Keys must be given in the form: "key1.nestedKey1.nestedKey2"
-func NestedMapLookup(m map[string]any, keys string) (any, error) {
-	ks := strings.Split(keys, ".")
-	if len(ks) == 0 {
-		return nil, fmt.Errorf("no keys given to find on map")
+// ParseStringJSONString parses a string value on the path of a JSON string,
+// see LookupJSONPath for the paths supported. An empty path parses the whole payload.
+func ParseStringJSONString(r string, key string) (string, error) {
+	document, err := DecodeJSONPayload(r, key)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := LookupJSONPath(document, key)
+	if err != nil {
+		return "", err
 	}
 
-	value, ok := m[ks[0]]
+	str, ok := value.(string)
 	if !ok {
-		return nil, fmt.Errorf("key %s not found", ks[0])
+		return "", fmt.Errorf("error parsing field %s: invalid type for payload: %T", key, value)
 	}
-	if len(ks) == 1 {
-		return value, nil
+
+	return str, nil
+}
+
+// ParseScalarJSONString parses the string, number or boolean value on the path of a JSON string
+// as a string, see LookupJSONPath for the paths supported. An empty path parses the whole payload.
+func ParseScalarJSONString(r string, key string) (string, error) {
+	document, err := DecodeJSONPayload(r, key)
+	if err != nil {
+		return "", err
 	}
-	nestedMap, ok := value.(map[string]any)
-	if !ok {
-		return nil, fmt.Errorf("nested key is not of type map[string]any")
+
+	value, err := LookupJSONPath(document, key)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
 	}
 
-	return NestedMapLookup(nestedMap, strings.Join(ks[1:], "."))
+	return "", fmt.Errorf("error parsing field %s: invalid type for payload: %T", key, value)
 }
@@ -1,56 +1,129 @@
 package utils
 
 import (
-	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 )
 
-func TestParseIntegerFromPayload(t *testing.T) {
+func TestParseIntegerJSONString(t *testing.T) {
 	c := require.New(t)
 
-	number, err := ParseIntegerFromPayload(bytes.NewReader([]byte(`{"ajua":12}`)), "ajua")
+	number, err := ParseIntegerJSONString(`{"ajua":12}`, "ajua")
 	c.NoError(err)
 	c.Equal(int64(12), number)
 
-	number, err = ParseIntegerFromPayload(bytes.NewReader([]byte(`{"ajua":"12"}`)), "ajua")
+	number, err = ParseIntegerJSONString(`{"ajua":"12"}`, "ajua")
 	c.NoError(err)
 	c.Equal(int64(12), number)
 
-	number, err = ParseIntegerFromPayload(bytes.NewReader([]byte(`{"ajua":["12"]}`)), "ajua")
+	number, err = ParseIntegerJSONString(`{"ajua":["12"]}`, "ajua")
 	c.EqualError(err, "error parsing field ajua: invalid type for payload: []interface {}")
 	c.Empty(number)
 }
 
-func TestParseIntegerJSONString(t *testing.T) {
+func TestParseNestedIntegerJSONString(t *testing.T) {
 	c := require.New(t)
 
-	number, err := ParseIntegerJSONString(`{"ajua":12}`, "ajua")
+	number, err := ParseIntegerJSONString(`{"ajua": {"papolo": 12}}`, "ajua.papolo")
 	c.NoError(err)
 	c.Equal(int64(12), number)
 
-	number, err = ParseIntegerJSONString(`{"ajua":"12"}`, "ajua")
+	number, err = ParseIntegerJSONString(`{"ajua": {"papolo": "12"}}`, "ajua.papolo")
 	c.NoError(err)
 	c.Equal(int64(12), number)
 
-	number, err = ParseIntegerJSONString(`{"ajua":["12"]}`, "ajua")
-	c.EqualError(err, "error parsing field ajua: invalid type for payload: []interface {}")
+	number, err = ParseIntegerJSONString(`{"ajua": {"papolo": [12]}}`, "ajua.papolo")
+	c.EqualError(err, "error parsing field ajua.papolo: invalid type for payload: []interface {}")
 	c.Empty(number)
 }
 
-func TestParseNestedIntegerJSONString(t *testing.T) {
+func TestParseStringJSONString(t *testing.T) {
 	c := require.New(t)
 
-	number, err := ParseIntegerJSONString(`{"ajua": {"papolo": 12}}`, "ajua.papolo")
+	hash, err := ParseStringJSONString(`{"result": {"hash": "0xabc"}}`, "result.hash")
+	c.NoError(err)
+	c.Equal("0xabc", hash)
+
+	_, err = ParseStringJSONString(`{"result": {"hash": 12}}`, "result.hash")
+	c.EqualError(err, "error parsing field result.hash: invalid type for payload: json.Number")
+
+	hash, err = ParseStringJSONString(`[{"result": "0xabc"}, {"result": "0xdef"}]`, "[-1].result")
+	c.NoError(err)
+	c.Equal("0xdef", hash)
+}
+
+func TestParseIntegerJSONStringFormats(t *testing.T) {
+	c := require.New(t)
+
+	number, err := ParseIntegerJSONString(`{"result":"0x10"}`, "result")
+	c.NoError(err)
+	c.Equal(int64(16), number)
+
+	number, err = ParseIntegerJSONString(`{"result":" 12 "}`, "result")
 	c.NoError(err)
 	c.Equal(int64(12), number)
 
-	number, err = ParseIntegerJSONString(`{"ajua": {"papolo": "12"}}`, "ajua.papolo")
+	// Exponents and fractions are rejected instead of truncated
+	_, err = ParseIntegerJSONString(`{"result":1.2e3}`, "result")
+	c.EqualError(err, "error parsing field result: invalid integer")
+
+	_, err = ParseIntegerJSONString(`{"result":"100.7"}`, "result")
+	c.EqualError(err, "error parsing field result: invalid integer")
+
+	_, err = ParseIntegerJSONString(`{"result":"1e20000000"}`, "result")
+	c.EqualError(err, "error parsing field result: invalid integer")
+
+	_, err = ParseIntegerJSONString(`{"result":"0x1g"}`, "result")
+	c.EqualError(err, "error parsing field result: invalid hex integer")
+
+	_, err = ParseBigIntegerJSONString(`{"result":"`+strings.Repeat("9", 79)+`"}`, "result")
+	c.EqualError(err, "error parsing field result: integer exceeds 78 digits")
+
+	// Plain text bodies are parsed with an empty path
+	number, err = ParseIntegerJSONString("0x1a\n", "")
+	c.NoError(err)
+	c.Equal(int64(26), number)
+
+	number, err = ParseIntegerJSONString(`12345`, "")
+	c.NoError(err)
+	c.Equal(int64(12345), number)
+
+	_, err = ParseIntegerJSONString(`not json`, "result")
+	c.Error(err)
+
+	_, err = ParseIntegerJSONString(`{"result":"0xffffffffffffffffffff"}`, "result")
+	c.EqualError(err, "error parsing field result: integer overflows int64")
+
+	integer, err := ParseBigIntegerJSONString(`{"result":123456789012345678901234567890}`, "result")
+	c.NoError(err)
+	c.Equal("123456789012345678901234567890", integer.String())
+}
+
+func TestParseIntegerJSONStringPaths(t *testing.T) {
+	c := require.New(t)
+
+	// Solana like responses
+	number, err := ParseIntegerJSONString(`{"result":{"context":{"slot":10},"value":[{"slot":"11"},{"slot":"12"}]}}`, "result.value[1].slot")
 	c.NoError(err)
 	c.Equal(int64(12), number)
 
-	number, err = ParseIntegerJSONString(`{"ajua": {"papolo": [12]}}`, "ajua.papolo")
-	c.EqualError(err, "error parsing field ajua.papolo: invalid type for payload: []interface {}")
-	c.Empty(number)
+	// Batch responses with wildcards use the highest value
+	number, err = ParseIntegerJSONString(`[{"id":1,"result":"0x10"},{"id":2,"error":{}},{"id":3,"result":"0x20"}]`, "[*].result")
+	c.NoError(err)
+	c.Equal(int64(32), number)
+
+	number, err = ParseIntegerJSONString(`{"heights":{"a":5,"b":7}}`, "heights.*")
+	c.NoError(err)
+	c.Equal(int64(7), number)
+
+	_, err = ParseIntegerJSONString(`[{"result":"0x10"}]`, "[1].result")
+	c.EqualError(err, "index 1 out of range")
+
+	_, err = ParseIntegerJSONString(`[{"error":{}}]`, "[*].result")
+	c.EqualError(err, "no values found on path [*].result")
+
+	_, err = ParseIntegerJSONString(`{"result":"0x10"}`, "result[0")
+	c.EqualError(err, "invalid path result[0: unclosed bracket")
 }
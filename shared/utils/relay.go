@@ -7,17 +7,44 @@ import (
 	"github.com/pokt-foundation/pocket-go/relayer"
 )
 
-// GetIntFromRelay performs a relay which result is expected to be an int and parses the result
-func GetIntFromRelay(Relayer relayer.Relayer, input relayer.Input, key string) (int64, error) {
+// IntegerParser parses the integer on the key of a payload
+type IntegerParser func(payload, key string) (int64, error)
+
+// GetParsedIntFromRelay performs a relay and parses its result as an int with the parser given,
+// the relay output is also returned so it can be used as proof of the node's response
+func GetParsedIntFromRelay(Relayer relayer.Relayer, input relayer.Input, key string, parse IntegerParser) (int64, *relayer.Output, error) {
+	relay, err := Relayer.Relay(&input, nil)
+	if err != nil {
+		return 0, nil, errors.New("error relaying: " + err.Error())
+	}
+
+	result, err := parse(relay.RelayOutput.Response, key)
+	if err != nil {
+		return 0, relay, fmt.Errorf("error parsing key %s: %s", key, err.Error())
+	}
+
+	return result, relay, nil
+}
+
+// StringParser parses the string on the key of a payload
+type StringParser func(payload, key string) (string, error)
+
+// GetStringFromRelay performs a relay which result is expected to be a string and parses the result
+func GetStringFromRelay(Relayer relayer.Relayer, input relayer.Input, key string) (string, *relayer.Output, error) {
+	return GetParsedStringFromRelay(Relayer, input, key, ParseStringJSONString)
+}
+
+// GetParsedStringFromRelay performs a relay and parses its result as a string with the parser given
+func GetParsedStringFromRelay(Relayer relayer.Relayer, input relayer.Input, key string, parse StringParser) (string, *relayer.Output, error) {
 	relay, err := Relayer.Relay(&input, nil)
 	if err != nil {
-		return 0, errors.New("error relaying: " + err.Error())
+		return "", nil, errors.New("error relaying: " + err.Error())
 	}
 
-	result, err := ParseIntegerJSONString(relay.RelayOutput.Response, key)
+	result, err := parse(relay.RelayOutput.Response, key)
 	if err != nil {
-		return 0, fmt.Errorf("error parsing key %s: %s", key, err.Error())
+		return "", relay, fmt.Errorf("error parsing key %s: %s", key, err.Error())
 	}
 
-	return result, nil
+	return result, relay, nil
 }
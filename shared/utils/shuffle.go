@@ -6,8 +6,8 @@ import (
 )
 
 // Shuffle shuffles an ordered collection and returns a copy of the result
-func Shuffle[T any](items []*T) []*T {
-	itemsCopy := make([]*T, len(items))
+func Shuffle[T any](items []T) []T {
+	itemsCopy := make([]T, len(items))
 	copy(itemsCopy, items)
 
 	rand.Seed(time.Now().UnixNano())